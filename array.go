@@ -0,0 +1,416 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ArrayValue stores a slice as an array column. It is bound by crudgen for
+// fields tagged with `,array`, which must be one of []string, []int64,
+// []float64, or the corresponding slices of pointers (which allow NULL
+// elements).
+//
+// By default arrays are written as JSON arrays, which is how they're stored
+// in SQLite. PostgresDialect writes them using the native array literal
+// syntax instead. Both encodings are understood when scanning.
+type ArrayValue struct {
+	ptr interface{}
+}
+
+// Array wraps ptr, a pointer to one of the slice types supported by
+// ArrayValue.
+func Array(ptr interface{}) *ArrayValue {
+	return &ArrayValue{ptr}
+}
+
+// Value implements driver.Valuer by encoding the slice as a JSON array. A nil
+// slice is stored as NULL.
+func (v *ArrayValue) Value() (driver.Value, error) {
+	elems, er := v.elements()
+	if er != nil || elems == nil {
+		return nil, er
+	}
+
+	for _, elem := range elems {
+		if f, ok := elem.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, fmt.Errorf("crud2: cannot store %v in a JSON array", f)
+		}
+	}
+
+	bs, er := json.Marshal(elems)
+	if er != nil {
+		return nil, er
+	}
+
+	return string(bs), nil
+}
+
+// Scan implements sql.Scanner, accepting both JSON arrays and PostgreSQL
+// array literals.
+func (v *ArrayValue) Scan(src interface{}) error {
+	var text string
+
+	switch src := src.(type) {
+	case nil:
+		return v.setElements(nil)
+
+	case []byte:
+		text = string(src)
+
+	case string:
+		text = src
+
+	default:
+		return fmt.Errorf("crud2: cannot scan %T into an array", src)
+	}
+
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "[") && !hasArrayBounds(text) {
+		var raw []interface{}
+
+		// Numbers are kept in their textual form, as decoding them into
+		// float64 would mangle large integers.
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+
+		if er := dec.Decode(&raw); er != nil {
+			return er
+		}
+
+		elems := make([]*string, len(raw))
+
+		for i, elem := range raw {
+			switch elem := elem.(type) {
+			case nil:
+			case string:
+				elems[i] = &elem
+			case json.Number:
+				s := elem.String()
+				elems[i] = &s
+			default:
+				return fmt.Errorf("crud2: unsupported JSON array element %T", elem)
+			}
+		}
+
+		return v.setElements(elems)
+	}
+
+	elems, er := parsePostgresArray(text)
+	if er != nil {
+		return er
+	}
+
+	return v.setElements(elems)
+}
+
+// elements flattens the slice into string, int64 or float64 values, with nil
+// standing in for NULL elements. A nil slice returns nil.
+func (v *ArrayValue) elements() ([]interface{}, error) {
+	var elems []interface{}
+
+	switch ptr := v.ptr.(type) {
+	case *[]string:
+		if *ptr != nil {
+			elems = make([]interface{}, len(*ptr))
+			for i, elem := range *ptr {
+				elems[i] = elem
+			}
+		}
+
+	case *[]*string:
+		if *ptr != nil {
+			elems = make([]interface{}, len(*ptr))
+			for i, elem := range *ptr {
+				if elem != nil {
+					elems[i] = *elem
+				}
+			}
+		}
+
+	case *[]int64:
+		if *ptr != nil {
+			elems = make([]interface{}, len(*ptr))
+			for i, elem := range *ptr {
+				elems[i] = elem
+			}
+		}
+
+	case *[]*int64:
+		if *ptr != nil {
+			elems = make([]interface{}, len(*ptr))
+			for i, elem := range *ptr {
+				if elem != nil {
+					elems[i] = *elem
+				}
+			}
+		}
+
+	case *[]float64:
+		if *ptr != nil {
+			elems = make([]interface{}, len(*ptr))
+			for i, elem := range *ptr {
+				elems[i] = elem
+			}
+		}
+
+	case *[]*float64:
+		if *ptr != nil {
+			elems = make([]interface{}, len(*ptr))
+			for i, elem := range *ptr {
+				if elem != nil {
+					elems[i] = *elem
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("crud2: unsupported array type %T", v.ptr)
+	}
+
+	return elems, nil
+}
+
+// setElements parses elems, the textual form of each element (nil for NULL),
+// into the slice. A nil elems sets the slice to nil.
+func (v *ArrayValue) setElements(elems []*string) error {
+	var er error
+
+	notNull := func(i int) bool {
+		if elems[i] == nil && er == nil {
+			er = fmt.Errorf("crud2: NULL element in array scanned into %T", v.ptr)
+		}
+		return elems[i] != nil
+	}
+
+	switch ptr := v.ptr.(type) {
+	case *[]string:
+		*ptr = nil
+		if elems != nil {
+			*ptr = make([]string, len(elems))
+			for i := range elems {
+				if notNull(i) {
+					(*ptr)[i] = *elems[i]
+				}
+			}
+		}
+
+	case *[]*string:
+		*ptr = nil
+		if elems != nil {
+			*ptr = make([]*string, len(elems))
+			copy(*ptr, elems)
+		}
+
+	case *[]int64:
+		*ptr = nil
+		if elems != nil {
+			*ptr = make([]int64, len(elems))
+			for i := range elems {
+				if notNull(i) && er == nil {
+					(*ptr)[i], er = strconv.ParseInt(*elems[i], 10, 64)
+				}
+			}
+		}
+
+	case *[]*int64:
+		*ptr = nil
+		if elems != nil {
+			*ptr = make([]*int64, len(elems))
+			for i := range elems {
+				if elems[i] != nil && er == nil {
+					(*ptr)[i] = new(int64)
+					*(*ptr)[i], er = strconv.ParseInt(*elems[i], 10, 64)
+				}
+			}
+		}
+
+	case *[]float64:
+		*ptr = nil
+		if elems != nil {
+			*ptr = make([]float64, len(elems))
+			for i := range elems {
+				if notNull(i) && er == nil {
+					(*ptr)[i], er = strconv.ParseFloat(*elems[i], 64)
+				}
+			}
+		}
+
+	case *[]*float64:
+		*ptr = nil
+		if elems != nil {
+			*ptr = make([]*float64, len(elems))
+			for i := range elems {
+				if elems[i] != nil && er == nil {
+					(*ptr)[i] = new(float64)
+					*(*ptr)[i], er = strconv.ParseFloat(*elems[i], 64)
+				}
+			}
+		}
+
+	default:
+		return fmt.Errorf("crud2: unsupported array type %T", v.ptr)
+	}
+
+	return er
+}
+
+// postgresArray encodes an ArrayValue as a PostgreSQL array literal.
+type postgresArray struct {
+	*ArrayValue
+}
+
+func (v postgresArray) Value() (driver.Value, error) {
+	elems, er := v.elements()
+	if er != nil || elems == nil {
+		return nil, er
+	}
+
+	parts := make([]string, len(elems))
+
+	for i, elem := range elems {
+		switch elem := elem.(type) {
+		case nil:
+			parts[i] = "NULL"
+
+		case string:
+			parts[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(elem) + `"`
+
+		case int64:
+			parts[i] = strconv.FormatInt(elem, 10)
+
+		case float64:
+			switch {
+			case math.IsInf(elem, 1):
+				parts[i] = "Infinity"
+			case math.IsInf(elem, -1):
+				parts[i] = "-Infinity"
+			default:
+				parts[i] = strconv.FormatFloat(elem, 'g', -1, 64)
+			}
+		}
+	}
+
+	return "{" + strings.Join(parts, ",") + "}", nil
+}
+
+// hasArrayBounds reports whether text starts with the dimensions PostgreSQL
+// prefixes arrays with non-default bounds with, e.g. "[0:1]={a,b}". These
+// are told apart from JSON arrays by the "]=" following the bounds.
+func hasArrayBounds(text string) bool {
+	end := strings.Index(text, "]=")
+	if !strings.HasPrefix(text, "[") || end < 0 {
+		return false
+	}
+
+	return strings.Trim(text[1:end], "0123456789-:][") == ""
+}
+
+// parsePostgresArray splits a one-dimensional PostgreSQL array literal into
+// its elements, with nil for NULL elements.
+func parsePostgresArray(text string) ([]*string, error) {
+	// Arrays with non-default bounds are prefixed with their dimensions,
+	// e.g. "[0:1]={a,b}".
+	if hasArrayBounds(text) {
+		text = text[strings.Index(text, "]=")+2:]
+	}
+
+	if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+		return nil, fmt.Errorf("crud2: malformed array literal %q", text)
+	}
+
+	body := text[1 : len(text)-1]
+	elems := []*string{}
+
+	if strings.TrimSpace(body) == "" {
+		return elems, nil
+	}
+
+	for pos := 0; ; {
+		for pos < len(body) && body[pos] == ' ' {
+			pos++
+		}
+
+		if pos < len(body) && body[pos] == '{' {
+			return nil, fmt.Errorf("crud2: multi-dimensional array literal %q is not supported", text)
+		}
+
+		var elem *string
+
+		if pos < len(body) && body[pos] == '"' {
+			var sb strings.Builder
+
+			for pos++; ; pos++ {
+				if pos >= len(body) {
+					return nil, fmt.Errorf("crud2: unterminated quote in array literal %q", text)
+				}
+
+				if body[pos] == '\\' && pos+1 < len(body) {
+					pos++
+				} else if body[pos] == '"' {
+					pos++
+					break
+				}
+
+				sb.WriteByte(body[pos])
+			}
+
+			s := sb.String()
+			elem = &s
+
+			for pos < len(body) && body[pos] == ' ' {
+				pos++
+			}
+
+		} else {
+			end := strings.IndexByte(body[pos:], ',')
+			if end < 0 {
+				end = len(body) - pos
+			}
+
+			s := strings.TrimSpace(body[pos : pos+end])
+			pos += end
+
+			if !strings.EqualFold(s, "NULL") {
+				elem = &s
+			}
+		}
+
+		elems = append(elems, elem)
+
+		if pos >= len(body) {
+			return elems, nil
+		}
+
+		if body[pos] != ',' {
+			return nil, fmt.Errorf("crud2: malformed array literal %q", text)
+		}
+
+		pos++
+	}
+}
+
+// postgresValues returns values with every ArrayValue replaced by its
+// PostgreSQL encoding.
+func postgresValues(values []interface{}) []interface{} {
+	out := make([]interface{}, len(values))
+
+	for i, value := range values {
+		switch value := value.(type) {
+		case *ArrayValue:
+			out[i] = postgresArray{value}
+
+		case SecretValue:
+			out[i] = Secret(postgresValues([]interface{}{value.v})[0])
+
+		default:
+			out[i] = value
+		}
+	}
+
+	return out
+}
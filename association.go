@@ -0,0 +1,156 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Associate links a and b in joinTable, a join table with one column named
+// after the `,pk` column of each of them:
+//
+//	CREATE TABLE post_tag
+//		( post_id INTEGER NOT NULL REFERENCES post (post_id)
+//		, tag_id INTEGER NOT NULL REFERENCES tag (tag_id)
+//		, PRIMARY KEY (post_id, tag_id)
+//		);
+//
+//	crud.Associate(db, post, tag, "post_tag")
+//
+// The row is inserted with ON CONFLICT DO NOTHING, so associating a pair that
+// is already linked is a no-op as long as the join table has a primary key or
+// unique constraint over both columns.
+func Associate(db DbIsh, a, b FieldEnumerator, joinTable string) error {
+	aColumn, aKey, er := primaryKey(a)
+	if er != nil {
+		return er
+	}
+
+	bColumn, bKey, er := primaryKey(b)
+	if er != nil {
+		return er
+	}
+
+	if er := checkJoinColumns(a, b, aColumn, bColumn); er != nil {
+		return er
+	}
+
+	_, er = db.Exec(associateQuery(joinTable, aColumn, bColumn), aKey, bKey)
+	return er
+}
+
+// Dissociate removes the link between a and b from joinTable, if there is one.
+func Dissociate(db DbIsh, a, b FieldEnumerator, joinTable string) error {
+	aColumn, aKey, er := primaryKey(a)
+	if er != nil {
+		return er
+	}
+
+	bColumn, bKey, er := primaryKey(b)
+	if er != nil {
+		return er
+	}
+
+	if er := checkJoinColumns(a, b, aColumn, bColumn); er != nil {
+		return er
+	}
+
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s = $1 AND %s = $2", joinTable, aColumn, bColumn)
+
+	_, er = db.Exec(q, aKey, bKey)
+	return er
+}
+
+// ReplaceAssociations links a in joinTable to exactly the structs in related,
+// a slice of structs or pointers to structs, removing any other links a had
+// to their type. An empty slice removes all of them. The changes are made in
+// a transaction, or a savepoint if db is already one.
+func ReplaceAssociations(db DbIsh, a FieldEnumerator, related interface{}, joinTable string) error {
+	aColumn, aKey, er := primaryKey(a)
+	if er != nil {
+		return er
+	}
+
+	elems, ptrType, er := structPointers(related)
+	if er != nil {
+		return er
+	}
+
+	// The join column is read from a new element, so that it's known
+	// even when related is empty.
+	sample, ok := reflect.New(ptrType.Elem()).Interface().(FieldEnumerator)
+	if !ok {
+		return fmt.Errorf("crud2: %s does not implement FieldEnumerator", ptrType)
+	}
+
+	bColumn, _, er := primaryKey(sample)
+	if er != nil {
+		return er
+	}
+
+	if er := checkJoinColumns(a, sample, aColumn, bColumn); er != nil {
+		return er
+	}
+
+	bKeys := make([]interface{}, len(elems))
+
+	for i, elem := range elems {
+		if _, bKeys[i], er = primaryKey(elem.(FieldEnumerator)); er != nil {
+			return er
+		}
+	}
+
+	return runTx(contextOf(db), db, nil, func(tx DbIsh) error {
+		q := fmt.Sprintf("DELETE FROM %s WHERE %s = $1", joinTable, aColumn)
+
+		if _, er := tx.Exec(q, aKey); er != nil {
+			return er
+		}
+
+		q = associateQuery(joinTable, aColumn, bColumn)
+
+		for _, bKey := range bKeys {
+			if _, er := tx.Exec(q, aKey, bKey); er != nil {
+				return er
+			}
+		}
+
+		return nil
+	})
+}
+
+func associateQuery(joinTable, aColumn, bColumn string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES ($1, $2) ON CONFLICT DO NOTHING", joinTable, aColumn, bColumn)
+}
+
+// checkJoinColumns rejects pairs of structs whose primary keys have the same
+// name, as they can't both be columns of the join table.
+func checkJoinColumns(a, b interface{}, aColumn, bColumn string) error {
+	if aColumn == bColumn {
+		return fmt.Errorf("crud2: can't join %T and %T, as both of their primary keys are named %s", a, b, aColumn)
+	}
+
+	return nil
+}
+
+// primaryKey returns the column of obj tagged `,pk` and its value.
+func primaryKey(obj FieldEnumerator) (column string, value interface{}, er error) {
+	var pks []string
+
+	if flagger, ok := obj.(FieldFlagger); ok {
+		pks = flagger.FlaggedFields("pk")
+	}
+
+	if len(pks) != 1 {
+		return "", nil, fmt.Errorf("crud2: %T needs exactly one column tagged ,pk", obj)
+	}
+
+	if value, _, er = columnValue(obj, pks[0]); er != nil {
+		return "", nil, er
+	}
+
+	if value == nil {
+		return "", nil, fmt.Errorf("crud2: %T has a NULL primary key", obj)
+	}
+
+	return pks[0], value, nil
+}
@@ -0,0 +1,93 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+)
+
+type contextDb struct {
+	DbIsh
+	ctx context.Context
+}
+
+// WithContext returns a DbIsh that runs queries against db using ctx, if db
+// supports it (as sql.DB, sql.Tx and sql.Conn do). ctx is also passed to any
+// lifecycle hooks invoked through the returned DbIsh.
+func WithContext(ctx context.Context, db DbIsh) DbIsh {
+	return contextDb{db, ctx}
+}
+
+func (db contextDb) Unwrap() DbIsh {
+	return db.DbIsh
+}
+
+func (db contextDb) Exec(q string, args ...interface{}) (sql.Result, error) {
+	return execContext(db.ctx, db.DbIsh, q, args...)
+}
+
+func (db contextDb) Prepare(q string) (*sql.Stmt, error) {
+	return prepareContext(db.ctx, db.DbIsh, q)
+}
+
+func (db contextDb) Query(q string, args ...interface{}) (*sql.Rows, error) {
+	return queryContext(db.ctx, db.DbIsh, q, args...)
+}
+
+// Context returns the context db was wrapped with.
+func (db contextDb) Context() context.Context {
+	return db.ctx
+}
+
+// contextOf returns the context db runs its queries with, as set by
+// WithContext or BeginTx, or context.Background() if there is none.
+func contextOf(db DbIsh) context.Context {
+	for db != nil {
+		if contexter, ok := db.(interface {
+			Context() context.Context
+		}); ok {
+			return contexter.Context()
+		}
+
+		wrapper, ok := db.(unwrapper)
+		if !ok {
+			break
+		}
+
+		db = wrapper.Unwrap()
+	}
+
+	return context.Background()
+}
+
+// execContext runs q against db using ctx, if db supports it.
+func execContext(ctx context.Context, db DbIsh, q string, args ...interface{}) (sql.Result, error) {
+	if execer, ok := db.(interface {
+		ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	}); ok {
+		return execer.ExecContext(ctx, q, args...)
+	}
+
+	return db.Exec(q, args...)
+}
+
+// prepareContext prepares q on db using ctx, if db supports it.
+func prepareContext(ctx context.Context, db DbIsh, q string) (*sql.Stmt, error) {
+	if preparer, ok := db.(interface {
+		PrepareContext(context.Context, string) (*sql.Stmt, error)
+	}); ok {
+		return preparer.PrepareContext(ctx, q)
+	}
+
+	return db.Prepare(q)
+}
+
+// queryContext runs q against db using ctx, if db supports it.
+func queryContext(ctx context.Context, db DbIsh, q string, args ...interface{}) (*sql.Rows, error) {
+	if queryer, ok := db.(interface {
+		QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	}); ok {
+		return queryer.QueryContext(ctx, q, args...)
+	}
+
+	return db.Query(q, args...)
+}
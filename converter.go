@@ -0,0 +1,151 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"sync"
+)
+
+type converter struct {
+	toDB   func(v reflect.Value) (driver.Value, error)
+	fromDB func(src interface{}, dst reflect.Value) error
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[reflect.Type]*converter{}
+)
+
+// RegisterConverter teaches crud how to store values of type T, which is
+// useful for third-party types that can't be tagged or wrapped. toDB returns
+// the value written to the database; fromDB parses a scanned column, which is
+// nil for NULL. Pointers to T are handled as well, with nil pointers mapping
+// to NULL and back.
+//
+// Converters apply to every value enumerated for Insert or Update and every
+// destination bound by Scan, whether or not it was generated by crudgen.
+// They should be registered before any queries are run, typically from init.
+// Registering a second converter for the same type replaces the first.
+func RegisterConverter[T any](toDB func(T) (driver.Value, error), fromDB func(src interface{}) (T, error)) {
+	c := &converter{
+		toDB: func(v reflect.Value) (driver.Value, error) {
+			return toDB(v.Interface().(T))
+		},
+		fromDB: func(src interface{}, dst reflect.Value) error {
+			value, er := fromDB(src)
+			if er != nil {
+				return er
+			}
+
+			dst.Set(reflect.ValueOf(&value).Elem())
+			return nil
+		},
+	}
+
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+
+	converters[reflect.TypeFor[T]()] = c
+}
+
+func lookupConverter(typ reflect.Type) *converter {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	return converters[typ]
+}
+
+// convertArg returns the driver value of arg if its type, or the type it
+// points to, has a registered converter. Otherwise arg is returned as-is.
+func convertArg(arg interface{}) (interface{}, error) {
+	if arg == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(arg)
+
+	for {
+		if c := lookupConverter(v.Type()); c != nil {
+			return c.toDB(v)
+		}
+
+		if v.Kind() != reflect.Pointer || v.IsNil() {
+			return arg, nil
+		}
+
+		v = v.Elem()
+	}
+}
+
+// convertArgs applies convertArg to each of args.
+func convertArgs(args []interface{}) ([]interface{}, error) {
+	out := make([]interface{}, len(args))
+
+	for i, arg := range args {
+		var er error
+
+		if out[i], er = convertArg(arg); er != nil {
+			return nil, er
+		}
+	}
+
+	return out, nil
+}
+
+// convertDest wraps dst, a destination for rows.Scan, in a convertedScanner
+// if it points to a type with a registered converter (or to a pointer to
+// one). Otherwise dst is returned as-is.
+func convertDest(dst interface{}) interface{} {
+	v := reflect.ValueOf(dst)
+
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return dst
+	}
+
+	elem := v.Type().Elem()
+
+	if c := lookupConverter(elem); c != nil {
+		return &convertedScanner{c, v.Elem(), false}
+	}
+
+	if elem.Kind() == reflect.Pointer {
+		if c := lookupConverter(elem.Elem()); c != nil {
+			return &convertedScanner{c, v.Elem(), true}
+		}
+	}
+
+	return dst
+}
+
+// convertDests applies convertDest to each of dsts, in place.
+func convertDests(dsts []interface{}) {
+	for i, dst := range dsts {
+		dsts[i] = convertDest(dst)
+	}
+}
+
+type convertedScanner struct {
+	c        *converter
+	dst      reflect.Value
+	nullable bool
+}
+
+func (s *convertedScanner) Scan(src interface{}) error {
+	if !s.nullable {
+		return s.c.fromDB(src, s.dst)
+	}
+
+	if src == nil {
+		s.dst.SetZero()
+		return nil
+	}
+
+	ptr := reflect.New(s.dst.Type().Elem())
+
+	if er := s.c.fromDB(src, ptr.Elem()); er != nil {
+		return er
+	}
+
+	s.dst.Set(ptr)
+	return nil
+}
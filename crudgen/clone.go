@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// cloneWriter accumulates the body of a generated Clone method.
+type cloneWriter struct {
+	gen    *Generator
+	bs     bytes.Buffer
+	indent int
+	tmp    int
+
+	// copying holds the struct types being deep-copied, so that types
+	// that refer to themselves through pointers are only copied one level
+	// deep rather than forever.
+	copying map[string]bool
+}
+
+func (w *cloneWriter) line(format string, args ...interface{}) {
+	w.bs.WriteString(strings.Repeat("\t", w.indent))
+	fmt.Fprintf(&w.bs, format, args...)
+	w.bs.WriteString("\n")
+}
+
+func (w *cloneWriter) temp(prefix string) string {
+	w.tmp++
+	return fmt.Sprintf("%s%d", prefix, w.tmp)
+}
+
+// needsDeepCopy reports whether copying a value of typ by assignment would
+// leave the copy sharing memory with the original. Structs and arrays need a
+// deep copy if any of their members do; struct fields the generated code
+// can't access, such as those of time.Time, are not considered.
+func (gen *Generator) needsDeepCopy(typ types.Type) bool {
+	switch u := typ.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map:
+		return true
+
+	case *types.Array:
+		return gen.needsDeepCopy(u.Elem())
+
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if field := u.Field(i); gen.canAccess(field) && gen.needsDeepCopy(field.Type()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// canAccess reports whether generated code can refer to field.
+func (gen *Generator) canAccess(field *types.Var) bool {
+	return field.Exported() || field.Pkg() == gen.Package
+}
+
+// deepCopy emits statements that set dst to a deep copy of src. Nothing is
+// emitted for types that are safely copied by assignment. Arrays and structs
+// are copied by assignment along with their parent, so only their members
+// that need it are deep-copied.
+func (w *cloneWriter) deepCopy(dst, src string, typ types.Type) {
+	switch u := typ.Underlying().(type) {
+	case *types.Pointer:
+		tmp := w.temp("p")
+
+		w.line("if %s != nil {", src)
+		w.indent++
+		w.line("%s := new(%s)", tmp, w.gen.typeString(u.Elem()))
+		w.line("*%s = *%s", tmp, src)
+		w.deepCopy("(*"+tmp+")", "(*"+src+")", u.Elem())
+		w.line("%s = %s", dst, tmp)
+		w.indent--
+		w.line("}")
+
+	case *types.Slice:
+		w.line("if %s != nil {", src)
+		w.indent++
+		w.line("%s = make(%s, len(%s))", dst, w.gen.typeString(typ), src)
+		w.line("copy(%s, %s)", dst, src)
+
+		if w.gen.needsDeepCopy(u.Elem()) {
+			idx := w.temp("i")
+
+			w.line("for %s := range %s {", idx, src)
+			w.indent++
+			w.deepCopy(dst+"["+idx+"]", src+"["+idx+"]", u.Elem())
+			w.indent--
+			w.line("}")
+		}
+
+		w.indent--
+		w.line("}")
+
+	case *types.Array:
+		if !w.gen.needsDeepCopy(u.Elem()) {
+			return
+		}
+
+		idx := w.temp("i")
+
+		w.line("for %s := range %s {", idx, src)
+		w.indent++
+		w.deepCopy(dst+"["+idx+"]", src+"["+idx+"]", u.Elem())
+		w.indent--
+		w.line("}")
+
+	case *types.Struct:
+		// Not typeString, which would import the packages of types
+		// nothing is emitted for.
+		name := types.TypeString(typ, nil)
+		if w.copying[name] {
+			return
+		}
+
+		w.copying[name] = true
+		defer delete(w.copying, name)
+
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+
+			if w.gen.canAccess(field) {
+				w.deepCopy(dst+"."+field.Name(), src+"."+field.Name(), field.Type())
+			}
+		}
+
+	case *types.Map:
+		key, val := w.temp("k"), w.temp("v")
+
+		w.line("if %s != nil {", src)
+		w.indent++
+		w.line("%s = make(%s, len(%s))", dst, w.gen.typeString(typ), src)
+		w.line("for %s, %s := range %s {", key, val, src)
+		w.indent++
+
+		if w.gen.needsDeepCopy(u.Elem()) {
+			elem := w.temp("c")
+
+			w.line("%s := %s", elem, val)
+			w.deepCopy(elem, val, u.Elem())
+			val = elem
+		}
+
+		w.line("%s[%s] = %s", dst, key, val)
+		w.indent--
+		w.line("}")
+		w.indent--
+		w.line("}")
+	}
+}
+
+// cloneBody returns the statements of a Clone method that copies the struct
+// into a variable named clone, deep-copying all tagged members and pointer
+// `,recurse` members.
+func (gen *Generator) cloneBody(structType *StructType) string {
+	w := &cloneWriter{gen: gen, indent: 1, copying: map[string]bool{}}
+	copied := map[string]bool{}
+
+	for _, field := range structType.Fields {
+		for i, step := range field.Pointers {
+			if copied[step.Path] {
+				continue
+			}
+
+			copied[step.Path] = true
+
+			guards := make([]string, i)
+
+			for j, parent := range field.Pointers[:i] {
+				guards[j] = fmt.Sprintf("self.%s != nil", parent.Path)
+			}
+
+			if len(guards) > 0 {
+				w.line("if %s {", strings.Join(guards, " && "))
+				w.indent++
+			}
+
+			w.deepCopy("clone."+step.Path, "self."+step.Path, step.Type)
+
+			if len(guards) > 0 {
+				w.indent--
+				w.line("}")
+			}
+		}
+
+		if !gen.needsDeepCopy(field.Type) {
+			continue
+		}
+
+		if len(field.Pointers) > 0 {
+			w.line("if %s {", field.NilGuard())
+			w.indent++
+		}
+
+		w.deepCopy("clone."+field.Name, "self."+field.Name, field.Type)
+
+		if len(field.Pointers) > 0 {
+			w.indent--
+			w.line("}")
+		}
+	}
+
+	return w.bs.String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"sort"
+	"strconv"
+)
+
+// ImportSet tracks the packages referenced by generated code and the names
+// they're imported under.
+type ImportSet struct {
+	local  *types.Package
+	byPath map[string]string
+	byName map[string]string
+}
+
+func NewImportSet(local *types.Package) *ImportSet {
+	return &ImportSet{
+		local:  local,
+		byPath: map[string]string{},
+		byName: map[string]string{},
+	}
+}
+
+// Add records an import of path, preferring to refer to it as name. The name
+// actually used is returned, which differs from the requested one if another
+// package already claimed it.
+func (set *ImportSet) Add(path, name string) string {
+	if existing, ok := set.byPath[path]; ok {
+		return existing
+	}
+
+	alias := name
+
+	for i := 2; set.byName[alias] != "" || alias == set.local.Name(); i++ {
+		alias = fmt.Sprintf("%s%d", name, i)
+	}
+
+	set.byPath[path] = alias
+	set.byName[alias] = path
+
+	return alias
+}
+
+// Qualifier is a types.Qualifier that imports every package other than the
+// one being generated.
+func (set *ImportSet) Qualifier(pkg *types.Package) string {
+	if pkg == set.local {
+		return ""
+	}
+
+	return set.Add(pkg.Path(), pkg.Name())
+}
+
+// String renders the import block body, one spec per line.
+func (set *ImportSet) String() string {
+	paths := make([]string, 0, len(set.byPath))
+
+	for path := range set.byPath {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	bs := &bytes.Buffer{}
+
+	for _, path := range paths {
+		fmt.Fprintf(bs, "\t%s %s\n", set.byPath[path], strconv.Quote(path))
+	}
+
+	return bs.String()
+}
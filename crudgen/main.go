@@ -6,6 +6,7 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"log"
 	"os"
 	"path/filepath"
@@ -13,6 +14,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"golang.org/x/tools/go/packages"
 )
 
 const (
@@ -21,11 +24,98 @@ const (
 )
 
 type StructType struct {
-	TypeSpec   *ast.TypeSpec
-	StructType *ast.StructType
+	Named  *types.Named
+	Struct *types.Struct
 
 	Name   string
 	Fields StructFieldList
+
+	// Relations holds the `,hasmany` and `,belongsto` fields, in declaration
+	// order.
+	Relations []RelationField
+
+	// CloneBody holds the statements of the generated Clone method.
+	CloneBody string
+}
+
+// NullStructs returns the distinct pointer `,recurse` members of the struct's
+// fields, in the order BindFields needs to declare them.
+func (structType StructType) NullStructs() []PointerStep {
+	seen := map[string]bool{}
+	steps := []PointerStep{}
+
+	for _, field := range structType.Fields {
+		for _, step := range field.Pointers {
+			if !seen[step.Var] {
+				seen[step.Var] = true
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	return steps
+}
+
+// FlagColumns lists the columns of a struct that carry a flag.
+type FlagColumns struct {
+	Flag    string
+	Columns []string
+}
+
+// Flags returns the flagged columns of the struct, ordered by flag, for the
+// generated FlaggedFields method.
+func (structType StructType) Flags() []FlagColumns {
+	byFlag := map[string][]string{}
+
+	for _, field := range structType.Fields {
+		for flag := range field.Flags {
+			byFlag[flag] = append(byFlag[flag], field.SqlName)
+		}
+	}
+
+	flags := make([]FlagColumns, 0, len(byFlag))
+
+	for flag, columns := range byFlag {
+		flags = append(flags, FlagColumns{flag, columns})
+	}
+
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Flag < flags[j].Flag
+	})
+
+	return flags
+}
+
+// SoftDelete reports whether the struct has a `,deleted` column, in which
+// case the generated fetch helpers skip soft-deleted rows.
+func (structType StructType) SoftDelete() bool {
+	for _, field := range structType.Fields {
+		if field.Flags.Has("deleted") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RelationField is a field tagged `,hasmany=table.column`,
+// `,belongsto=table.column` or `,manytomany=join_table.table`, for which
+// crudgen emits a crud.Relation.
+type RelationField struct {
+	Pos token.Pos
+
+	Name string
+	// Kind is the name of the crud.RelationKind constant.
+	Kind string
+	// Table and Column name the related table and its column matched
+	// against Key, a column of this struct.
+	Table  string
+	Column string
+	Key    string
+	// JoinTable is the join table of a ManyToMany relation.
+	JoinTable string
+	// Elem is the qualified name of the related struct type.
+	Elem string
 }
 
 func (structType StructType) Metadata() string {
@@ -42,18 +132,199 @@ func (structType StructType) Metadata() string {
 	return bs.String()
 }
 
+// PointerStep is a pointer-typed `,recurse` member that has to be dereferenced
+// on the way to a StructField. Its columns are scanned through a
+// crud.NullStruct, so that the member stays nil if they are all NULL.
+type PointerStep struct {
+	// Path is the Go selector of the pointer member relative to self.
+	Path string
+	// Type is the pointer type of the member.
+	Type types.Type
+	// Elem is the qualified name of the type pointed to.
+	Elem string
+	// Var is the name of the NullStruct variable in BindFields.
+	Var string
+	// Ptr is the address of the pointer member, relative to its parent
+	// NullStruct's Value when nested.
+	Ptr string
+	// Parent is the Var of the enclosing PointerStep, or "nil".
+	Parent string
+}
+
+// FieldFlags holds the flags following the column name in a crud tag, e.g.
+// `crud:"settings,json"`. Flags of the form `key=value` map key to value, and
+// plain flags map to "".
+type FieldFlags map[string]string
+
+func parseFieldFlags(flags []string) FieldFlags {
+	out := FieldFlags{}
+
+	for _, flag := range flags {
+		key, value, _ := strings.Cut(flag, "=")
+		out[key] = value
+	}
+
+	return out
+}
+
+func (flags FieldFlags) Has(flag string) bool {
+	_, ok := flags[flag]
+	return ok
+}
+
 type StructField struct {
 	Name    string
 	SqlName string
-	Type    ast.Expr
+	Type    types.Type
+	Flags   FieldFlags
+
+	// Pointers lists the pointer `,recurse` members between self and this
+	// field, outermost first.
+	Pointers []PointerStep
+
+	// BindTarget is the Go expression BindFields scans the column into.
+	BindTarget string
 }
 
-func (f StructField) EnumAddr() bool {
-	if id, ok := f.Type.(*ast.Ident); ok {
-		switch id.Name {
-		case "int64":
-			return false
-		case "string":
+// codec wraps ptr, the address of the field, in the crud helper that encodes
+// and decodes the column according to the field's flags. If the column is
+// handled natively by database/sql, "" is returned.
+func (f StructField) codec(ptr string) string {
+	switch {
+	case f.Flags.Has("json"):
+		return fmt.Sprintf("crud.JSON(%s)", ptr)
+
+	case f.Flags.Has("array"):
+		return fmt.Sprintf("crud.Array(%s)", ptr)
+
+	case f.Flags.Has("text"):
+		if _, ok := f.Type.(*types.Pointer); ok {
+			return fmt.Sprintf("crud.NullText(%s, %s)", strconv.Quote(f.SqlName), ptr)
+		}
+
+		return fmt.Sprintf("crud.Text(%s, %s)", strconv.Quote(f.SqlName), ptr)
+
+	case f.Flags.Has("nullzero"):
+		return fmt.Sprintf("crud.NullZero(%s)", ptr)
+	}
+
+	return ""
+}
+
+// BindValue returns the expression BindFields stores for the column.
+func (f StructField) BindValue() string {
+	ptr := "&" + f.BindTarget
+	wrapped := f.codec(ptr)
+
+	if n := len(f.Pointers); n > 0 {
+		if wrapped != "" {
+			return fmt.Sprintf("crud.NullScanner(%s, %s)", f.Pointers[n-1].Var, wrapped)
+		}
+
+		return fmt.Sprintf("crud.NullField(%s, %s)", f.Pointers[n-1].Var, ptr)
+	}
+
+	if wrapped != "" {
+		return wrapped
+	}
+
+	return ptr
+}
+
+// EnumValue returns the expression EnumerateFields emits for the column.
+// Fields are always enumerated by address; the dialects dereference them and
+// apply any converters registered with crud.RegisterConverter.
+func (f StructField) EnumValue() string {
+	if wrapped := f.codec("&self." + f.Name); wrapped != "" {
+		return wrapped
+	}
+
+	return "&self." + f.Name
+}
+
+// NilGuard returns an expression that is true when none of the pointers
+// leading to this field are nil.
+func (f StructField) NilGuard() string {
+	guards := make([]string, len(f.Pointers))
+
+	for i, ptr := range f.Pointers {
+		guards[i] = fmt.Sprintf("self.%s != nil", ptr.Path)
+	}
+
+	return strings.Join(guards, " && ")
+}
+
+// Generator holds the state needed to turn a type-checked package into
+// crud metadata.
+type Generator struct {
+	Fset    *token.FileSet
+	Package *types.Package
+	Imports *ImportSet
+}
+
+func (gen *Generator) errorf(pos token.Pos, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", gen.Fset.Position(pos), fmt.Sprintf(format, args...))
+}
+
+// recurseTarget resolves the type of a `,recurse` field to the struct that
+// should be flattened into its parent. Both named structs (from any package)
+// and pointers to them are accepted.
+func (gen *Generator) recurseTarget(field *types.Var) (named *types.Named, st *types.Struct, isPtr bool, er error) {
+	typ := field.Type()
+
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+		isPtr = true
+	}
+
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok {
+		return nil, nil, false, gen.errorf(field.Pos(), "'recurse' field %s has type %s, which is not a named struct", field.Name(), gen.typeString(field.Type()))
+	}
+
+	st, ok = named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, nil, false, gen.errorf(field.Pos(), "'recurse' field %s has type %s, which is not a struct", field.Name(), gen.typeString(field.Type()))
+	}
+
+	return named, st, isPtr, nil
+}
+
+// isArrayType reports whether typ is one of the slice types supported by
+// crud.Array.
+func isArrayType(typ types.Type) bool {
+	slice, ok := typ.(*types.Slice)
+	if !ok {
+		return false
+	}
+
+	elem := slice.Elem()
+
+	if ptr, ok := elem.(*types.Pointer); ok {
+		elem = ptr.Elem()
+	}
+
+	if basic, ok := elem.(*types.Basic); ok {
+		switch basic.Kind() {
+		case types.String, types.Int64, types.Float64:
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTextType reports whether a pointer to typ implements both
+// encoding.TextMarshaler and encoding.TextUnmarshaler. Pointer types are
+// checked by their element type, as they are bound by crud.NullText.
+func isTextType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	for _, method := range []string{"MarshalText", "UnmarshalText"} {
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(typ), true, nil, method)
+		if _, ok := obj.(*types.Func); !ok {
 			return false
 		}
 	}
@@ -61,124 +332,445 @@ func (f StructField) EnumAddr() bool {
 	return true
 }
 
-func fileFilter(fi os.FileInfo) bool {
-	name := fi.Name()
+// isPointerToTime reports whether typ is *time.Time.
+func isPointerToTime(typ types.Type) bool {
+	_, ok := typ.(*types.Pointer)
+	return ok && isTimeType(typ)
+}
+
+// isTimeType reports whether typ is time.Time or *time.Time.
+func isTimeType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
 
-	if !strings.HasSuffix(name, ".go") {
+	named, ok := typ.(*types.Named)
+	if !ok {
 		return false
 	}
 
-	return fi.Name() != outputFilename
+	obj := named.Obj()
+
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time"
+}
+
+func (gen *Generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, gen.Imports.Qualifier)
 }
 
-func buildStructType(structType *StructType, astStruct *ast.StructType, prefix string) {
-	for _, field := range astStruct.Fields.List {
-		if field.Tag == nil {
+// fieldPath describes how to reach the members of a (possibly nested) struct.
+type fieldPath struct {
+	// prefix is the selector prefix relative to self, e.g. "In.Deep.".
+	prefix string
+	// base is the expression BindFields addresses members from; either
+	// "self" or the Value of the innermost NullStruct.
+	base string
+	// rel is the selector prefix relative to base.
+	rel string
+	// pointers lists the pointer members traversed so far.
+	pointers []PointerStep
+}
+
+func (gen *Generator) buildStructType(structType *StructType, st *types.Struct, path fieldPath, visiting map[*types.Named]bool) error {
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+
+		if field.Embedded() {
+			// XXX: We may want to support anonymous fields in the future.
 			continue
 		}
 
-		if len(field.Names) == 0 {
-			// XXX: We may want to support anonymous fields in the future.
+		tag := reflect.StructTag(st.Tag(i)).Get(structTagName)
+		if tag == "" {
 			continue
 		}
 
-		name := field.Names[0].Name
+		name := field.Name()
 
-		tagList, er := strconv.Unquote(field.Tag.Value)
-		if er != nil {
-			continue
+		if !field.Exported() && field.Pkg() != gen.Package {
+			return gen.errorf(field.Pos(), "field %s is tagged but not exported from package %s", name, field.Pkg().Path())
 		}
 
-		rtags := reflect.StructTag(tagList)
+		tagList := strings.Split(tag, ",")
+		flags := parseFieldFlags(tagList[1:])
 
-		if tag := rtags.Get(structTagName); tag != "" {
-			tagList := strings.Split(tag, ",")
+		if flags.Has("recurse") {
+			// The "recurse" flag is valid only on structs (or pointers
+			// to structs), and indicates that all fields of the tagged
+			// struct should be included as well.
+			named, sub, isPtr, er := gen.recurseTarget(field)
+			if er != nil {
+				return er
+			}
 
-			if len(tagList) > 1 {
-				if tagList[1] == "recurse" {
-					// The "recurse" flag is valid only on structs, and
-					// indicates that all fields of the tagged struct
-					// should be included as well.
-					// XXX: This is a bit of a mess.
-					ident, ok := field.Type.(*ast.Ident)
-					if !ok {
-						panic("'recurse' Field type is not an ast.Ident?")
-					}
-					if ident.Obj == nil || ident.Obj.Decl == nil {
-						panic("'recurse' Field type doesn't refer to an object")
-					}
+			if visiting[named] {
+				return gen.errorf(field.Pos(), "'recurse' field %s refers back to %s", name, gen.typeString(named))
+			}
 
-					ts, ok := ident.Obj.Decl.(*ast.TypeSpec)
-					if !ok || ts.Type == nil {
-						panic("'recurse' Field type declaration doesn't ... have ... a TypeSpec?")
-					}
+			subPath := fieldPath{
+				prefix:   path.prefix + name + ".",
+				base:     path.base,
+				rel:      path.rel + name + ".",
+				pointers: path.pointers,
+			}
 
-					st, ok := ts.Type.(*ast.StructType)
-					if !ok {
-						panic("'recurse' field isn't a struct")
-					}
+			if isPtr {
+				step := PointerStep{
+					Path:   path.prefix + name,
+					Type:   field.Type(),
+					Elem:   gen.typeString(named),
+					Var:    "null" + strings.ReplaceAll(path.prefix+name, ".", ""),
+					Ptr:    "&" + path.base + "." + path.rel + name,
+					Parent: "nil",
+				}
 
-					buildStructType(structType, st, prefix + name + ".")
+				if n := len(path.pointers); n > 0 {
+					step.Parent = path.pointers[n-1].Var
 				}
+
+				subPath.base = step.Var + ".Value"
+				subPath.rel = ""
+				subPath.pointers = append(path.pointers[:len(path.pointers):len(path.pointers)], step)
 			}
 
-			if tagList[0] != "" {
-				// NB: Intentionally skip entries like `,recurse`.
-				structType.Fields = append(structType.Fields, StructField{
-					Name:    prefix + name,
-					SqlName: tagList[0],
-					Type:    field.Type,
-				})
+			visiting[named] = true
+			er = gen.buildStructType(structType, sub, subPath, visiting)
+			delete(visiting, named)
+
+			if er != nil {
+				return er
+			}
+		}
+
+		if flags.Has("array") && !isArrayType(field.Type()) {
+			return gen.errorf(field.Pos(), "'array' field %s has type %s; only []string, []int64 and []float64 (or slices of pointers to them) are supported", name, gen.typeString(field.Type()))
+		}
+
+		if flags.Has("text") && !isTextType(field.Type()) {
+			return gen.errorf(field.Pos(), "'text' field %s has type %s, which doesn't implement encoding.TextMarshaler and encoding.TextUnmarshaler", name, gen.typeString(field.Type()))
+		}
+
+		for _, flag := range []string{"created", "updated"} {
+			if !flags.Has(flag) {
+				continue
+			}
+
+			if !isTimeType(field.Type()) {
+				return gen.errorf(field.Pos(), "'%s' field %s has type %s; only time.Time and *time.Time are supported", flag, name, gen.typeString(field.Type()))
+			}
+
+			if (StructField{Flags: flags}).codec("") != "" {
+				return gen.errorf(field.Pos(), "'%s' field %s can't be combined with flags that change how it is stored", flag, name)
+			}
+		}
+
+		if flags.Has("deleted") && !isPointerToTime(field.Type()) {
+			return gen.errorf(field.Pos(), "'deleted' field %s has type %s; only *time.Time is supported", name, gen.typeString(field.Type()))
+		}
+
+		if relationFlag(flags) != "" {
+			// Relationship fields hold other structs rather than a
+			// column, so they're recorded separately.
+			relation, er := gen.relationField(field, tagList[0], flags, path)
+			if er != nil {
+				return er
 			}
+
+			structType.Relations = append(structType.Relations, relation)
+			continue
+		}
+
+		if tagList[0] != "" {
+			// NB: Intentionally skip entries like `,recurse`.
+			structType.Fields = append(structType.Fields, StructField{
+				Name:       path.prefix + name,
+				SqlName:    tagList[0],
+				Type:       field.Type(),
+				Flags:      flags,
+				Pointers:   path.pointers,
+				BindTarget: path.base + "." + path.rel + name,
+			})
 		}
 	}
 
-	sort.Sort(structType.Fields)
+	return nil
 }
 
-func main() {
-	dirPath := "."
+// relationFlags lists the flags that declare relations, and relationKinds
+// maps them to the crud.RelationKind constants they declare.
+var (
+	relationFlags = []string{"belongsto", "hasmany", "manytomany"}
+	relationKinds = map[string]string{
+		"belongsto":  "BelongsTo",
+		"hasmany":    "HasMany",
+		"manytomany": "ManyToMany",
+	}
+)
 
-	if len(os.Args) > 1 {
-		dirPath = os.Args[1]
+// relationFlag returns the relation flag among flags, or "" if there is none.
+func relationFlag(flags FieldFlags) string {
+	for _, flag := range relationFlags {
+		if flags.Has(flag) {
+			return flag
+		}
+	}
+
+	return ""
+}
+
+// relationField parses the tag of a `,hasmany`, `,belongsto` or `,manytomany`
+// field. The column name of a `,belongsto` tag names the foreign key column;
+// that of a `,hasmany` tag may name the key the related rows refer to, which
+// otherwise defaults to the `,pk` column and is filled in by
+// resolveRelations. `,manytomany` relations always join the `,pk` columns of
+// both structs.
+func (gen *Generator) relationField(field *types.Var, column string, flags FieldFlags, path fieldPath) (RelationField, error) {
+	name := field.Name()
+	flag := relationFlag(flags)
+	kind := relationKinds[flag]
+
+	for _, other := range relationFlags {
+		if other != flag && flags.Has(other) {
+			return RelationField{}, gen.errorf(field.Pos(), "field %s can't be both '%s' and '%s'", name, flag, other)
+		}
+	}
+
+	if path.prefix != "" {
+		return RelationField{}, gen.errorf(field.Pos(), "'%s' field %s is inside a 'recurse' field; relations must be declared on the struct itself", flag, name)
+	}
+
+	table, relColumn, ok := strings.Cut(flags[flag], ".")
+	joinTable := ""
+
+	if kind == "ManyToMany" {
+		if !ok || table == "" || relColumn == "" {
+			return RelationField{}, gen.errorf(field.Pos(), "'manytomany' field %s must name the join table and the related table, e.g. manytomany=join_table.table", name)
+		}
+
+		if column != "" {
+			return RelationField{}, gen.errorf(field.Pos(), "'manytomany' field %s can't name a column; the join table's columns are named after the ',pk' columns", name)
+		}
+
+		joinTable, table, relColumn = table, relColumn, ""
+	} else if !ok || table == "" || relColumn == "" {
+		return RelationField{}, gen.errorf(field.Pos(), "'%s' field %s must name the related table and column, e.g. %s=table.column", flag, name, flag)
+	}
+
+	typ := field.Type()
+	want := "*T"
+
+	if kind != "BelongsTo" {
+		want = "[]*T"
+
+		slice, ok := typ.(*types.Slice)
+		if !ok {
+			return RelationField{}, gen.errorf(field.Pos(), "'%s' field %s has type %s; only %s of a named struct T is supported", flag, name, gen.typeString(field.Type()), want)
+		}
+
+		typ = slice.Elem()
+	}
+
+	var elem *types.Named
+
+	if ptr, ok := typ.(*types.Pointer); ok {
+		elem, _ = types.Unalias(ptr.Elem()).(*types.Named)
+	}
+
+	if elem == nil {
+		return RelationField{}, gen.errorf(field.Pos(), "'%s' field %s has type %s; only %s of a named struct T is supported", flag, name, gen.typeString(field.Type()), want)
+	}
+
+	st, ok := elem.Underlying().(*types.Struct)
+	if !ok {
+		return RelationField{}, gen.errorf(field.Pos(), "'%s' field %s has type %s; only %s of a named struct T is supported", flag, name, gen.typeString(field.Type()), want)
+	}
+
+	if kind == "BelongsTo" && column == "" {
+		return RelationField{}, gen.errorf(field.Pos(), "'belongsto' field %s must name its foreign key column, e.g. `crud:\"owner_id,belongsto=%s\"`", name, flags[flag])
+	}
+
+	if kind == "ManyToMany" {
+		pks := pkColumns(st)
+		if len(pks) != 1 {
+			return RelationField{}, gen.errorf(field.Pos(), "'manytomany' field %s needs exactly one field of %s tagged ',pk'", name, gen.typeString(elem))
+		}
+
+		relColumn = pks[0]
+	}
+
+	return RelationField{
+		Pos:       field.Pos(),
+		Name:      name,
+		Kind:      kind,
+		Table:     table,
+		Column:    relColumn,
+		Key:       column,
+		JoinTable: joinTable,
+		Elem:      gen.typeString(elem),
+	}, nil
+}
+
+// pkColumns returns the columns of the fields of st tagged `,pk`.
+func pkColumns(st *types.Struct) []string {
+	pks := []string{}
+
+	for i := 0; i < st.NumFields(); i++ {
+		tagList := strings.Split(reflect.StructTag(st.Tag(i)).Get(structTagName), ",")
+
+		if tagList[0] != "" && parseFieldFlags(tagList[1:]).Has("pk") {
+			pks = append(pks, tagList[0])
+		}
+	}
+
+	return pks
+}
+
+// resolveRelations defaults the Key of `,hasmany` and `,manytomany` relations
+// to the struct's `,pk` column.
+func (gen *Generator) resolveRelations(structType *StructType) error {
+	pks := []string{}
+
+	for _, field := range structType.Fields {
+		if field.Flags.Has("pk") {
+			pks = append(pks, field.SqlName)
+		}
+	}
+
+	for i, relation := range structType.Relations {
+		if relation.Key != "" {
+			continue
+		}
+
+		if len(pks) != 1 && relation.Kind == "ManyToMany" {
+			return gen.errorf(relation.Pos, "'manytomany' field %s needs exactly one field of %s tagged ',pk'", relation.Name, structType.Name)
+		}
+
+		if len(pks) != 1 {
+			return gen.errorf(relation.Pos, "'hasmany' field %s needs exactly one field of %s tagged ',pk', or a key column, e.g. `crud:\"%s_id,hasmany=%s.%s\"`", relation.Name, structType.Name, strings.ToLower(structType.Name), relation.Table, relation.Column)
+		}
+
+		structType.Relations[i].Key = pks[0]
+	}
+
+	return nil
+}
+
+// loadPackage type-checks the package in dirPath. Any previously generated
+// output is replaced with an empty file so that stale metadata can't break
+// type-checking.
+//
+// Type errors are expected, as code that passes the package's structs to crud
+// doesn't compile without the methods crudgen is about to generate; the
+// declarations crudgen needs are still type-checked. Only errors listing or
+// parsing the package are returned.
+func loadPackage(dirPath string) (*packages.Package, error) {
+	absDir, er := filepath.Abs(dirPath)
+	if er != nil {
+		return nil, er
 	}
 
-	fset := new(token.FileSet)
+	overlay := map[string][]byte{}
+	outputPath := filepath.Join(absDir, outputFilename)
 
-	pkgs, er := parser.ParseDir(fset, dirPath, fileFilter, 0)
+	if f, er := parser.ParseFile(token.NewFileSet(), outputPath, nil, parser.PackageClauseOnly); er == nil {
+		overlay[outputPath] = []byte("package " + f.Name.Name + "\n")
+	}
+
+	// Dependencies are type-checked from source too. Otherwise go list
+	// compiles the package for export data, and reports the type errors
+	// above as fatal list errors.
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Dir:     absDir,
+		Overlay: overlay,
+	}
+
+	pkgs, er := packages.Load(cfg, ".")
 	if er != nil {
-		log.Fatal(er)
+		return nil, er
 	}
 
-	structTypes := StructTypeList{}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("Multiple packages found! crudgen only supports one package at a time.")
+	}
+
+	pkg := pkgs[0]
+
+	msgs := []string{}
+
+	for _, pkgEr := range pkg.Errors {
+		if pkgEr.Kind != packages.TypeError {
+			msgs = append(msgs, pkgEr.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(msgs, "\n"))
+	}
 
-	if len(pkgs) > 1 {
-		log.Fatal("Multiple packages found! crudgen only supports one package at a time.")
+	if pkg.Types == nil || pkg.TypesInfo == nil {
+		return nil, fmt.Errorf("%s: package could not be type-checked", dirPath)
 	}
 
-	var packageName string
+	return pkg, nil
+}
+
+// crudImportPath is the import path of the crud package generated code refers
+// to, always as "crud".
+const crudImportPath = "github.com/lye/crud2"
+
+// generate returns the contents of the z_crud2.go file for the package in
+// dirPath.
+func generate(dirPath string) ([]byte, error) {
+	pkg, er := loadPackage(dirPath)
+	if er != nil {
+		return nil, er
+	}
+
+	if pkg.PkgPath == crudImportPath || pkg.Name == "crud" {
+		return nil, fmt.Errorf("%s: package %s can't be generated, as generated code refers to %s as \"crud\"", dirPath, pkg.Name, crudImportPath)
+	}
+
+	gen := &Generator{
+		Fset:    pkg.Fset,
+		Package: pkg.Types,
+		Imports: NewImportSet(pkg.Types),
+	}
+
+	gen.Imports.Add(crudImportPath, "crud")
+
+	structTypes := StructTypeList{}
 
 	// Enumerate the AST and pull out all of the struct declarations.
-	for _, pkg := range pkgs {
-		packageName = pkg.Name
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok {
+				if genDecl.Tok != token.TYPE {
+					continue
+				}
 
-		for _, file := range pkg.Files {
-			for _, decl := range file.Decls {
-				if genDecl, ok := decl.(*ast.GenDecl); ok {
-					if genDecl.Tok != token.TYPE {
+				for _, spec := range genDecl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok || typeSpec.TypeParams != nil {
 						continue
 					}
 
-					for _, spec := range genDecl.Specs {
-						if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-							if structType, ok := typeSpec.Type.(*ast.StructType); ok {
-								structTypes = append(structTypes, &StructType{
-									TypeSpec:   typeSpec,
-									StructType: structType,
-									Name:       typeSpec.Name.Name,
-								})
-							}
-						}
+					obj, ok := pkg.TypesInfo.Defs[typeSpec.Name].(*types.TypeName)
+					if !ok || obj.IsAlias() {
+						continue
+					}
+
+					named, ok := obj.Type().(*types.Named)
+					if !ok {
+						continue
+					}
+
+					if st, ok := named.Underlying().(*types.Struct); ok {
+						structTypes = append(structTypes, &StructType{
+							Named:  named,
+							Struct: st,
+							Name:   obj.Name(),
+						})
 					}
 				}
 			}
@@ -188,7 +780,44 @@ func main() {
 
 	// Enumerate the structs we've pulled out and parse their field declarations.
 	for _, structType := range structTypes {
-		buildStructType(structType, structType.StructType, "")
+		visiting := map[*types.Named]bool{structType.Named: true}
+
+		if er := gen.buildStructType(structType, structType.Struct, fieldPath{base: "self"}, visiting); er != nil {
+			return nil, er
+		}
+
+		if er := gen.resolveRelations(structType); er != nil {
+			return nil, er
+		}
+
+		sort.Sort(structType.Fields)
+		structType.CloneBody = gen.cloneBody(structType)
+	}
+
+	bs := &bytes.Buffer{}
+
+	for _, structType := range structTypes {
+		fmt.Fprintf(bs, "%s", structType.Metadata())
+	}
+
+	out := &bytes.Buffer{}
+
+	fmt.Fprintf(out, "package %s\n// AUTOGENERATED CODE. Regenerate by running crudgen.\n\nimport (\n%s)\n", pkg.Name, gen.Imports)
+	fmt.Fprintf(out, "%s\n", bs.String())
+
+	return out.Bytes(), nil
+}
+
+func main() {
+	dirPath := "."
+
+	if len(os.Args) > 1 {
+		dirPath = os.Args[1]
+	}
+
+	out, er := generate(dirPath)
+	if er != nil {
+		log.Fatal(er)
 	}
 
 	filePath := filepath.Join(dirPath, outputFilename)
@@ -198,14 +827,10 @@ func main() {
 	}
 	defer f.Close()
 
-	fmt.Fprintf(f, "package %s\n// AUTOGENERATED CODE. Regenerate by running crudgen.\n\nimport (\n\t\"github.com/lye/crud2\"\n)\n", packageName)
-
-	for _, structType := range structTypes {
-		fmt.Fprintf(f, "%s", structType.Metadata())
+	if _, er := f.Write(out); er != nil {
+		log.Fatal(er)
 	}
 
-	fmt.Fprintf(f, "\n")
-
 	if er := f.Sync(); er != nil {
 		log.Fatal(er)
 	}
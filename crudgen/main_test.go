@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// typeErrors type-checks the package in dir as it is on disk, including any
+// generated file.
+func typeErrors(t *testing.T, dir string) []packages.Error {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+	}
+
+	pkgs, er := packages.Load(cfg, ".")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	return pkgs[0].Errors
+}
+
+func TestGenerate(t *testing.T) {
+	for _, name := range []string{"basic", "relations", "multi"} {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join("testdata", name)
+			golden := filepath.Join(dir, outputFilename+".golden")
+			output := filepath.Join(dir, outputFilename)
+
+			t.Cleanup(func() {
+				os.Remove(output)
+			})
+
+			out, er := generate(dir)
+			if er != nil {
+				t.Fatal(er)
+			}
+
+			if *update {
+				if er := os.WriteFile(golden, out, 0644); er != nil {
+					t.Fatal(er)
+				}
+			}
+
+			want, er := os.ReadFile(golden)
+			if er != nil {
+				t.Fatal(er)
+			}
+
+			if !bytes.Equal(out, want) {
+				t.Fatalf("Generated code doesn't match %s (rerun with -update):\n%s", golden, out)
+			}
+
+			if er := os.WriteFile(output, out, 0644); er != nil {
+				t.Fatal(er)
+			}
+
+			if errs := typeErrors(t, dir); len(errs) > 0 {
+				t.Fatalf("Generated code doesn't type-check: %v", errs)
+			}
+
+			// Rerunning over previous output, whether current or stale,
+			// has to give the same result.
+			stale := []byte("package " + name + "\n\nfunc (self *Stale) Missing() {}\n")
+
+			for _, previous := range [][]byte{out, stale} {
+				if er := os.WriteFile(output, previous, 0644); er != nil {
+					t.Fatal(er)
+				}
+
+				again, er := generate(dir)
+				if er != nil {
+					t.Fatalf("Rerunning crudgen failed: %s", er)
+				}
+
+				if !bytes.Equal(again, want) {
+					t.Errorf("Rerunning crudgen over\n%s\ngave different output:\n%s", previous, again)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := map[string]string{
+		"crudname": `package crud can't be generated, as generated code refers to github.com/lye/crud2 as "crud"`,
+		"badtag":   "badtag.go:5:2: 'recurse' field Settings has type int64, which is not a named struct",
+	}
+
+	for name, want := range tests {
+		_, er := generate(filepath.Join("testdata", name))
+
+		if er == nil || !strings.Contains(er.Error(), want) {
+			t.Errorf("Expected generating %s to fail with %q, got %v", name, want, er)
+		}
+	}
+}
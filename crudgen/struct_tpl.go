@@ -20,13 +20,24 @@ func fetch{{.Name}}(db crud.DbIsh, q string, args ...interface{}) (out *{{.Name}
 		panic(er)
 	}
 	defer rows.Close()
-
+{{if .SoftDelete}}
+	for rows.Next() {
+		c := new({{.Name}})
+		if er = crud.Scan(rows, c); er != nil {
+			panic(er)
+		}
+		if !crud.Hidden(db, c) {
+			return c
+		}
+	}
+{{- else}}
 	if rows.Next() {
 		out = new({{.Name}})
 		if er = crud.Scan(rows, out); er != nil {
 			panic(er)
 		}
 	}
+{{- end}}
 
 	return
 }
@@ -45,6 +56,11 @@ func fetch{{.Name}}Slice(db crud.DbIsh, q string, args ...interface{}) (out []*{
 		if er := crud.Scan(rows, c); er != nil {
 			panic(er)
 		}
+{{- if .SoftDelete}}
+		if crud.Hidden(db, c) {
+			continue
+		}
+{{- end}}
 		out = append(out, c)
 	}
 
@@ -52,11 +68,19 @@ func fetch{{.Name}}Slice(db crud.DbIsh, q string, args ...interface{}) (out []*{
 }
 
 func (self *{{.Name}}) BindFields(names []string, values []interface{}) {
+{{- range .NullStructs}}
+	var {{.Var}} *crud.NullStruct[{{.Elem}}]
+{{- end}}
+{{- if .NullStructs}}
+{{end}}
 	for i, name := range names {
 		switch name {
 {{range.Fields}}
 		case {{quote .SqlName}}:
-			values[i] = &self.{{.Name}}
+{{range .Pointers}}			if {{.Var}} == nil {
+				{{.Var}} = crud.NewNullStruct({{.Ptr}}, {{.Parent}})
+			}
+{{end}}			values[i] = {{.BindValue}}
 {{end}}
 		}
 	}
@@ -67,10 +91,77 @@ func (self *{{.Name}}) EnumerateFields() (names []string, values []interface{})
 	values = make([]interface{}, 0, {{length .Fields}})
 {{range .Fields}}
 	names = append(names, {{quote .SqlName}})
-	values = append(values, {{if .EnumAddr}}&{{end}}self.{{.Name}})
-{{end}}
+{{if .Pointers}}	if {{.NilGuard}} {
+		values = append(values, {{.EnumValue}})
+	} else {
+		values = append(values, nil)
+	}
+{{else}}	values = append(values, {{.EnumValue}})
+{{end}}{{end}}
 	return
 }
+
+{{if .Flags -}}
+func (self *{{.Name}}) FlaggedFields(flag string) []string {
+	switch flag {
+{{range .Flags}}
+	case {{quote .Flag}}:
+		return []string{ {{- range $i, $col := .Columns}}{{if $i}}, {{end}}{{quote $col}}{{end -}} }
+{{end}}
+	}
+
+	return nil
+}
+
+{{end -}}
+{{if .Relations -}}
+func (self *{{.Name}}) CrudRelation(name string) *crud.Relation {
+	switch name {
+{{range .Relations}}
+	case {{quote .Name}}:
+		return &crud.Relation{
+{{- if .JoinTable}}
+			Kind:      crud.{{.Kind}},
+			Table:     {{quote .Table}},
+			Key:       {{quote .Key}},
+			Column:    {{quote .Column}},
+			JoinTable: {{quote .JoinTable}},
+{{- else}}
+			Kind:   crud.{{.Kind}},
+			Table:  {{quote .Table}},
+			Key:    {{quote .Key}},
+			Column: {{quote .Column}},
+{{- end}}
+			New: func() crud.FieldBinder {
+				return new({{.Elem}})
+			},
+			Attach: func(related []crud.FieldBinder) {
+{{- if ne .Kind "BelongsTo"}}
+				self.{{.Name}} = make([]*{{.Elem}}, len(related))
+				for i, obj := range related {
+					self.{{.Name}}[i] = obj.(*{{.Elem}})
+				}
+{{- else}}
+				self.{{.Name}} = nil
+				if len(related) > 0 {
+					self.{{.Name}} = related[0].(*{{.Elem}})
+				}
+{{- end}}
+			},
+		}
+{{end}}
+	}
+
+	return nil
+}
+
+{{end -}}
+func (self *{{.Name}}) Clone() crud.FieldBinder {
+	clone := new({{.Name}})
+	*clone = *self
+{{.CloneBody}}
+	return clone
+}
 `
 
 var structTemplate = template.Must(template.New("").Funcs(tplFuncs).Parse(structTemplateStr))
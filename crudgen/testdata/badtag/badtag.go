@@ -0,0 +1,6 @@
+package badtag
+
+type Foo struct {
+	Id       int64 `crud:"foo_id"`
+	Settings int64 `crud:",recurse"`
+}
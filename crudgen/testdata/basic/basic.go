@@ -0,0 +1,57 @@
+package basic
+
+import (
+	"fmt"
+	"time"
+
+	crud "github.com/lye/crud2"
+)
+
+type Status int
+
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint(int(s))), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	_, er := fmt.Sscan(string(text), (*int)(s))
+	return er
+}
+
+type Point struct {
+	X, Y   float64
+	Labels []string
+}
+
+// Node refers to itself, so Clone only copies it one level deep.
+type Node struct {
+	Name string
+	Next *Node
+}
+
+type Owner struct {
+	Id   int64  `crud:"owner_id"`
+	Name string `crud:"owner_name,nullzero"`
+}
+
+type Pet struct {
+	Id      int64             `crud:"pet_id,pk"`
+	Name    *string           `crud:"pet_name"`
+	Tags    []string          `crud:"pet_tags,array"`
+	Extra   map[string]string `crud:"pet_extra,json"`
+	Status  Status            `crud:"pet_status,text"`
+	Created time.Time         `crud:"pet_created_at,created"`
+	Deleted *time.Time        `crud:"pet_deleted_at,deleted"`
+	Secret  string            `crud:"pet_secret,secret"`
+	Owner   *Owner            `crud:",recurse"`
+	Home    Point             `crud:"pet_home,json"`
+	Route   [2]Point          `crud:"pet_route,json"`
+	Born    time.Time         `crud:"pet_born"`
+	Family  *Node             `crud:"pet_family,json"`
+}
+
+// SavePet doesn't compile until crudgen has generated Pet's methods.
+func SavePet(db crud.DbIsh, pet *Pet) error {
+	_, er := crud.Insert(db, "pet", "pet_id", pet)
+	return er
+}
@@ -0,0 +1,5 @@
+package crud
+
+type Foo struct {
+	Id int64 `crud:"foo_id"`
+}
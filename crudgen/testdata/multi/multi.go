@@ -0,0 +1,20 @@
+package multi
+
+import (
+	"time"
+
+	"github.com/lye/crud2/crudgen/testdata/multi/sub"
+)
+
+// Owner and sub.Addr hold a time.Time, which Clone copies by assignment, so
+// the generated code mustn't import time.
+type Owner struct {
+	Id   int64     `crud:"owner_id"`
+	Seen time.Time `crud:"owner_seen"`
+}
+
+type Pet struct {
+	Id    int64     `crud:"pet_id,pk"`
+	Owner *Owner    `crud:",recurse"`
+	Addr  *sub.Addr `crud:",recurse"`
+}
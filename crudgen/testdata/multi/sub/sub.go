@@ -0,0 +1,8 @@
+package sub
+
+import "time"
+
+type Addr struct {
+	Street string    `crud:"addr_street"`
+	Since  time.Time `crud:"addr_since"`
+}
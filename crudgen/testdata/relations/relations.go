@@ -0,0 +1,23 @@
+package relations
+
+type Customer struct {
+	Id     int64    `crud:"customer_id,pk"`
+	Name   string   `crud:"customer_name"`
+	Orders []*Order `crud:",hasmany=orders.order_customer_id"`
+}
+
+type Order struct {
+	Id         int64     `crud:"order_id,pk"`
+	CustomerId int64     `crud:"order_customer_id"`
+	Customer   *Customer `crud:"order_customer_id,belongsto=customer.customer_id"`
+}
+
+type Post struct {
+	Id   int64  `crud:"post_id,pk"`
+	Tags []*Tag `crud:",manytomany=post_tag.tag"`
+}
+
+type Tag struct {
+	Id   int64  `crud:"tag_id,pk"`
+	Name string `crud:"tag_name"`
+}
@@ -0,0 +1,109 @@
+package crudtest_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	crud "github.com/lye/crud2"
+	"github.com/lye/crud2/crudtest"
+)
+
+type Widget struct {
+	Id    int64  `crud:"widget_id"`
+	Name  string `crud:"widget_name"`
+	Count int64  `crud:"widget_count"`
+}
+
+func TestInsertUpdate(t *testing.T) {
+	db, rec := crudtest.New(t)
+
+	w := &Widget{Name: "sprocket", Count: 3}
+
+	rec.ReturnResult(41, 1)
+
+	id, er := crud.Insert(db, "widget", "widget_id", w)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if id != 41 || w.Id != 41 {
+		t.Errorf("Expected scripted id 41, got %d (Id %d)", id, w.Id)
+	}
+
+	w.Count++
+
+	if er := crud.Update(db, "widget", "widget_id", w); er != nil {
+		t.Fatal(er)
+	}
+
+	stmt := rec.ExpectInsert("widget", "widget_name", "widget_count")
+
+	if stmt.Value("widget_name") != "sprocket" || stmt.Value("widget_count") != int64(3) {
+		t.Errorf("Unexpected INSERT arguments: %v", stmt.Args)
+	}
+
+	stmt = rec.ExpectUpdate("widget", "widget_name", "widget_count")
+
+	if stmt.Value("widget_count") != int64(4) || stmt.Args[len(stmt.Args)-1] != int64(41) {
+		t.Errorf("Unexpected UPDATE arguments: %v", stmt.Args)
+	}
+
+	rec.ExpectDone()
+}
+
+func TestScriptedRows(t *testing.T) {
+	db, rec := crudtest.New(t)
+
+	rec.ReturnRows([]string{"widget_id", "widget_name", "widget_count"},
+		[]driver.Value{int64(1), "a", int64(10)},
+		[]driver.Value{int64(2), "b", int64(20)},
+	)
+
+	widgets := []Widget{}
+
+	if er := crud.SelectWhere(db, "widget", &widgets, "widget_count > $1", 5); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(widgets) != 2 || widgets[1].Name != "b" || widgets[1].Count != 20 {
+		t.Errorf("Unexpected widgets: %#v", widgets)
+	}
+
+	stmt := rec.ExpectSelect("widget")
+
+	if len(stmt.Args) != 1 || stmt.Args[0] != int64(5) {
+		t.Errorf("Unexpected SELECT arguments: %v", stmt.Args)
+	}
+
+	rec.ExpectDone()
+}
+
+func TestScriptedErrors(t *testing.T) {
+	db, rec := crudtest.New(t)
+
+	locked := errors.New("database is locked")
+
+	rec.ReturnError(locked)
+
+	attempts := 0
+
+	er := crud.WithTx(context.Background(), db, &crud.TxOptions{Backoff: 1}, func(tx crud.DbIsh) error {
+		attempts++
+		_, er := crud.Insert(tx, "widget", "widget_id", &Widget{Name: "retry"})
+		return er
+	})
+
+	if er != nil || attempts != 2 {
+		t.Errorf("Expected one retry (attempts: %d, er: %v)", attempts, er)
+	}
+
+	rec.ExpectBegin()
+	rec.ExpectInsert("widget")
+	rec.ExpectRollback()
+	rec.ExpectBegin()
+	rec.ExpectInsert("widget")
+	rec.ExpectCommit()
+	rec.ExpectDone()
+}
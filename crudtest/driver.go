@@ -0,0 +1,144 @@
+package crudtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DriverName is the name the fake driver is registered under with
+// database/sql. Its DSNs name Recorders created by New.
+const DriverName = "crudtest"
+
+var (
+	recordersMu sync.Mutex
+	recorders   = map[string]*Recorder{}
+	recorderSeq int
+)
+
+func init() {
+	sql.Register(DriverName, fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	rec, ok := recorders[name]
+	if !ok {
+		return nil, fmt.Errorf("crudtest: no recorder named %q", name)
+	}
+
+	return &fakeConn{rec}, nil
+}
+
+type fakeConn struct {
+	rec *Recorder
+}
+
+func (c *fakeConn) Prepare(q string) (driver.Stmt, error) {
+	return &fakeStmt{c.rec, q}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.rec.record("BEGIN", nil)
+	return &fakeTx{c.rec}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	return c.rec.exec(q, values(args))
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.rec.query(q, values(args))
+}
+
+type fakeTx struct {
+	rec *Recorder
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.rec.record("COMMIT", nil)
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.rec.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeStmt struct {
+	rec *Recorder
+	q   string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.rec.exec(s.q, args)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.rec.query(s.q, args)
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+
+	for i, arg := range args {
+		out[i] = arg.Value
+	}
+
+	return out
+}
+
+type fakeResult struct {
+	lastInsertId, rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
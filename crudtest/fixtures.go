@@ -0,0 +1,203 @@
+package crudtest
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	crud "github.com/lye/crud2"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is implemented by pointers to crudgen-generated structs.
+type Fixture[T any] interface {
+	*T
+	crud.FieldBinder
+	crud.FieldEnumerator
+}
+
+// LoadFixtures inserts the records in the fixture file at name into table
+// with crud.Insert, so that hooks, timestamps and the like apply as usual,
+// and returns them with their primary keys set. The test fails if anything
+// goes wrong.
+//
+// Fixture files hold a list of records keyed by column name, as YAML (.yaml
+// or .yml) or JSON (.json):
+//
+//	# widgets.yaml
+//	- widget_name: sprocket
+//	  widget_count: 3
+//	- widget_name: gear
+func LoadFixtures[T any, PT Fixture[T]](t testing.TB, db crud.DbIsh, fsys fs.FS, name, table, sqlIdFieldName string) []*T {
+	t.Helper()
+
+	records, er := readFixtures(fsys, name)
+	if er != nil {
+		t.Fatalf("crudtest: loading %s: %v", name, er)
+	}
+
+	out := make([]*T, 0, len(records))
+
+	for i, record := range records {
+		obj := PT(new(T))
+
+		if er := bindRecord(obj, record); er != nil {
+			t.Fatalf("crudtest: %s record %d: %v", name, i, er)
+		}
+
+		if _, er := crud.Insert(db, table, sqlIdFieldName, obj); er != nil {
+			t.Fatalf("crudtest: inserting %s record %d: %v", name, i, er)
+		}
+
+		out = append(out, (*T)(obj))
+	}
+
+	return out
+}
+
+func readFixtures(fsys fs.FS, name string) ([]map[string]interface{}, error) {
+	bs, er := fs.ReadFile(fsys, name)
+	if er != nil {
+		return nil, er
+	}
+
+	var records []map[string]interface{}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		er = yaml.Unmarshal(bs, &records)
+
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(bs))
+		dec.UseNumber()
+		er = dec.Decode(&records)
+
+	default:
+		er = fmt.Errorf("unknown fixture format %q", path.Ext(name))
+	}
+
+	return records, er
+}
+
+// bindRecord sets the fields obj binds for the columns of record.
+func bindRecord(obj crud.FieldBinder, record map[string]interface{}) error {
+	names := make([]string, 0, len(record))
+	srcs := make([]interface{}, 0, len(record))
+
+	for name, src := range record {
+		names = append(names, strings.ToLower(name))
+		srcs = append(srcs, src)
+	}
+
+	dsts := make([]interface{}, len(names))
+	obj.BindFields(names, dsts)
+
+	for i, dst := range dsts {
+		if dst == nil {
+			return fmt.Errorf("no field is bound to column %s", names[i])
+		}
+
+		if er := assign(dst, srcs[i]); er != nil {
+			return fmt.Errorf("column %s: %w", names[i], er)
+		}
+	}
+
+	return nil
+}
+
+// assign stores src, a value decoded from a fixture file, into dst, a
+// destination returned by BindFields.
+func assign(dst, src interface{}) error {
+	if n, ok := src.(json.Number); ok {
+		if i, er := n.Int64(); er == nil {
+			src = i
+		} else if f, er := n.Float64(); er == nil {
+			src = f
+		}
+	}
+
+	if scanner, ok := dst.(sql.Scanner); ok {
+		// Structured values are handed to Scanners, such as the ones
+		// bound for `,json` fields, in their JSON encoding.
+		switch src.(type) {
+		case map[string]interface{}, []interface{}:
+			bs, er := json.Marshal(src)
+			if er != nil {
+				return er
+			}
+			src = string(bs)
+		}
+
+		return scanner.Scan(src)
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("cannot assign to %T", dst)
+	}
+
+	return assignValue(v.Elem(), src)
+}
+
+func assignValue(v reflect.Value, src interface{}) error {
+	if src == nil {
+		v.SetZero()
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+
+		if er := assignValue(elem.Elem(), src); er != nil {
+			return er
+		}
+
+		v.Set(elem)
+		return nil
+	}
+
+	if s, ok := src.(string); ok && v.Type() == reflect.TypeFor[time.Time]() {
+		t, er := time.Parse(time.RFC3339Nano, s)
+		if er != nil {
+			return er
+		}
+
+		src = t
+	}
+
+	sv := reflect.ValueOf(src)
+
+	switch {
+	case sv.Type().AssignableTo(v.Type()):
+		v.Set(sv)
+
+	case isNumber(sv.Kind()) && isNumber(v.Kind()):
+		v.Set(sv.Convert(v.Type()))
+
+	case sv.Kind() == v.Kind() && sv.Type().ConvertibleTo(v.Type()):
+		v.Set(sv.Convert(v.Type()))
+
+	default:
+		return fmt.Errorf("cannot assign %T to %s", src, v.Type())
+	}
+
+	return nil
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
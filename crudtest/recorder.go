@@ -0,0 +1,392 @@
+// Package crudtest helps test code built on crud2 without a real database.
+//
+// New opens an *sql.DB backed by a fake driver that records every statement
+// it receives and answers with scripted rows and results:
+//
+//	db, rec := crudtest.New(t)
+//	rec.ReturnResult(7, 1)
+//
+//	id, er := crud.Insert(db, "foo", "foo_id", foo)
+//
+//	stmt := rec.ExpectInsert("foo", "foo_num", "foo_str")
+//	if stmt.Value("foo_num") != int64(42) { ... }
+//	rec.ExpectDone()
+//
+// For tests that need a real database, NewSQLite opens an in-memory SQLite
+// database with a schema applied, and LoadFixtures fills it from YAML or
+// JSON files.
+package crudtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Statement is a statement received by the fake driver.
+type Statement struct {
+	// Query is the statement's SQL, with runs of whitespace collapsed.
+	Query string
+	Args  []driver.Value
+
+	// Op is the statement's leading keyword in upper case, e.g. "INSERT".
+	Op string
+	// Table is the table an INSERT, UPDATE, DELETE or SELECT operates on.
+	Table string
+	// Columns lists the columns written by an INSERT or UPDATE, in order.
+	Columns []string
+}
+
+// Value returns the argument written to column by an INSERT or UPDATE, or
+// nil if the statement doesn't write column.
+func (stmt Statement) Value(column string) driver.Value {
+	for i, col := range stmt.Columns {
+		if col == column && i < len(stmt.Args) {
+			return stmt.Args[i]
+		}
+	}
+
+	return nil
+}
+
+var (
+	insertRe = regexp.MustCompile(`(?i)^INSERT\s+INTO\s+(\S+)\s*\(([^)]*)\)`)
+	updateRe = regexp.MustCompile(`(?i)^UPDATE\s+(\S+)\s+SET\s+(.*?)(?:\s+WHERE\s|\s+RETURNING\s|$)`)
+	deleteRe = regexp.MustCompile(`(?i)^DELETE\s+FROM\s+(\S+)`)
+	selectRe = regexp.MustCompile(`(?i)^SELECT\s.*?\sFROM\s+(\S+)`)
+)
+
+func parseStatement(q string, args []driver.Value) Statement {
+	q = strings.Join(strings.Fields(q), " ")
+
+	stmt := Statement{
+		Query: q,
+		Args:  args,
+	}
+
+	if fields := strings.Fields(q); len(fields) > 0 {
+		stmt.Op = strings.ToUpper(fields[0])
+	}
+
+	splitColumns := func(list string) []string {
+		columns := []string{}
+
+		for _, col := range strings.Split(list, ",") {
+			col, _, _ = strings.Cut(col, "=")
+			columns = append(columns, strings.TrimSpace(col))
+		}
+
+		return columns
+	}
+
+	if m := insertRe.FindStringSubmatch(q); m != nil {
+		stmt.Table, stmt.Columns = m[1], splitColumns(m[2])
+
+	} else if m := updateRe.FindStringSubmatch(q); m != nil {
+		stmt.Table, stmt.Columns = m[1], splitColumns(m[2])
+
+	} else if m := deleteRe.FindStringSubmatch(q); m != nil {
+		stmt.Table = m[1]
+
+	} else if m := selectRe.FindStringSubmatch(q); m != nil {
+		stmt.Table = m[1]
+	}
+
+	return stmt
+}
+
+type response struct {
+	rows   *fakeRows
+	result *fakeResult
+	err    error
+}
+
+// Recorder records the statements run against a database opened by New, and
+// holds the responses scripted for them.
+type Recorder struct {
+	t testing.TB
+
+	mu           sync.Mutex
+	statements   []Statement
+	checked      int
+	responses    []response
+	lastInsertId int64
+}
+
+// New opens a database backed by the fake driver, recording into the
+// returned Recorder. Both are cleaned up when t finishes.
+func New(t testing.TB) (*sql.DB, *Recorder) {
+	rec := &Recorder{t: t}
+
+	recordersMu.Lock()
+	recorderSeq++
+	name := fmt.Sprintf("%s#%d", t.Name(), recorderSeq)
+	recorders[name] = rec
+	recordersMu.Unlock()
+
+	db, er := sql.Open(DriverName, name)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+
+		recordersMu.Lock()
+		delete(recorders, name)
+		recordersMu.Unlock()
+	})
+
+	return db, rec
+}
+
+// ReturnRows scripts the response to the next query: rows with the given
+// columns. Each row holds one value per column, which must be a
+// driver.Value (int64, float64, bool, []byte, string, time.Time or nil).
+func (rec *Recorder) ReturnRows(columns []string, rows ...[]driver.Value) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.responses = append(rec.responses, response{rows: &fakeRows{columns, rows}})
+}
+
+// ReturnResult scripts the result of the next Exec.
+func (rec *Recorder) ReturnResult(lastInsertId, rowsAffected int64) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.responses = append(rec.responses, response{result: &fakeResult{lastInsertId, rowsAffected}})
+}
+
+// ReturnError scripts an error for the next Exec or query.
+func (rec *Recorder) ReturnError(er error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.responses = append(rec.responses, response{err: er})
+}
+
+// Statements returns every statement recorded so far.
+func (rec *Recorder) Statements() []Statement {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append([]Statement(nil), rec.statements...)
+}
+
+func (rec *Recorder) record(q string, args []driver.Value) Statement {
+	stmt := parseStatement(q, args)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.statements = append(rec.statements, stmt)
+	return stmt
+}
+
+// respond pops the next scripted response.
+func (rec *Recorder) respond() (response, bool) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.responses) == 0 {
+		return response{}, false
+	}
+
+	resp := rec.responses[0]
+	rec.responses = rec.responses[1:]
+
+	return resp, true
+}
+
+// exec answers an Exec with the next scripted result. Without one, INSERTs
+// get sequential ids starting from 1, and every statement affects one row.
+func (rec *Recorder) exec(q string, args []driver.Value) (driver.Result, error) {
+	stmt := rec.record(q, args)
+
+	resp, ok := rec.respond()
+
+	switch {
+	case !ok:
+		if stmt.Op == "INSERT" {
+			rec.mu.Lock()
+			rec.lastInsertId++
+			id := rec.lastInsertId
+			rec.mu.Unlock()
+
+			return fakeResult{id, 1}, nil
+		}
+
+		return fakeResult{0, 1}, nil
+
+	case resp.err != nil:
+		return nil, resp.err
+
+	case resp.result == nil:
+		return nil, fmt.Errorf("crudtest: rows were scripted, but Exec was called for %q", stmt.Query)
+	}
+
+	return *resp.result, nil
+}
+
+// query answers a query with the next scripted rows, or no rows.
+func (rec *Recorder) query(q string, args []driver.Value) (driver.Rows, error) {
+	stmt := rec.record(q, args)
+
+	resp, ok := rec.respond()
+
+	switch {
+	case !ok:
+		return &fakeRows{}, nil
+
+	case resp.err != nil:
+		return nil, resp.err
+
+	case resp.rows == nil:
+		return nil, fmt.Errorf("crudtest: a result was scripted, but a query was run for %q", stmt.Query)
+	}
+
+	return resp.rows, nil
+}
+
+// next returns the oldest statement not yet checked by an Expect method.
+func (rec *Recorder) next(what string) (Statement, bool) {
+	rec.t.Helper()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.checked >= len(rec.statements) {
+		rec.t.Errorf("crudtest: expected %s, but no more statements were run", what)
+		return Statement{}, false
+	}
+
+	stmt := rec.statements[rec.checked]
+	rec.checked++
+
+	return stmt, true
+}
+
+func (rec *Recorder) expect(op, table string, columns []string) Statement {
+	rec.t.Helper()
+
+	what := op + " on " + table
+
+	stmt, ok := rec.next(what)
+	if !ok {
+		return stmt
+	}
+
+	if stmt.Op != op || !strings.EqualFold(stmt.Table, table) {
+		rec.t.Errorf("crudtest: expected %s, got %q", what, stmt.Query)
+		return stmt
+	}
+
+	if len(columns) > 0 && !sameColumns(stmt.Columns, columns) {
+		rec.t.Errorf("crudtest: expected %s of columns %v, got %v", what, columns, stmt.Columns)
+	}
+
+	return stmt
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	seen := map[string]int{}
+
+	for _, col := range a {
+		seen[strings.ToLower(col)]++
+	}
+
+	for _, col := range b {
+		if seen[strings.ToLower(col)]--; seen[strings.ToLower(col)] < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ExpectInsert checks that the next statement is an INSERT into table, and
+// if any columns are given, that it writes exactly those columns in any
+// order. The test fails otherwise. The statement is returned for further
+// checks.
+func (rec *Recorder) ExpectInsert(table string, columns ...string) Statement {
+	rec.t.Helper()
+	return rec.expect("INSERT", table, columns)
+}
+
+// ExpectUpdate is like ExpectInsert, for an UPDATE of table whose SET clause
+// writes columns.
+func (rec *Recorder) ExpectUpdate(table string, columns ...string) Statement {
+	rec.t.Helper()
+	return rec.expect("UPDATE", table, columns)
+}
+
+// ExpectDelete checks that the next statement is a DELETE from table.
+func (rec *Recorder) ExpectDelete(table string) Statement {
+	rec.t.Helper()
+	return rec.expect("DELETE", table, nil)
+}
+
+// ExpectSelect checks that the next statement is a SELECT from table.
+func (rec *Recorder) ExpectSelect(table string) Statement {
+	rec.t.Helper()
+	return rec.expect("SELECT", table, nil)
+}
+
+// ExpectQuery checks that the next statement is q, ignoring differences in
+// whitespace.
+func (rec *Recorder) ExpectQuery(q string) Statement {
+	rec.t.Helper()
+
+	q = strings.Join(strings.Fields(q), " ")
+
+	stmt, ok := rec.next(fmt.Sprintf("%q", q))
+	if ok && stmt.Query != q {
+		rec.t.Errorf("crudtest: expected %q, got %q", q, stmt.Query)
+	}
+
+	return stmt
+}
+
+// ExpectBegin checks that the next statement began a transaction.
+func (rec *Recorder) ExpectBegin() {
+	rec.t.Helper()
+	rec.ExpectQuery("BEGIN")
+}
+
+// ExpectCommit checks that the next statement committed a transaction.
+func (rec *Recorder) ExpectCommit() {
+	rec.t.Helper()
+	rec.ExpectQuery("COMMIT")
+}
+
+// ExpectRollback checks that the next statement rolled back a transaction.
+func (rec *Recorder) ExpectRollback() {
+	rec.t.Helper()
+	rec.ExpectQuery("ROLLBACK")
+}
+
+// ExpectDone checks that every statement has been checked by an Expect
+// method, and that every scripted response was used.
+func (rec *Recorder) ExpectDone() {
+	rec.t.Helper()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	for _, stmt := range rec.statements[rec.checked:] {
+		rec.t.Errorf("crudtest: unexpected statement %q", stmt.Query)
+	}
+
+	if len(rec.responses) > 0 {
+		rec.t.Errorf("crudtest: %d scripted responses were not used", len(rec.responses))
+	}
+}
@@ -0,0 +1,57 @@
+package crudtest
+
+import (
+	"database/sql"
+	"io/fs"
+	"sort"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// NewSQLite opens an isolated in-memory SQLite database and applies every
+// *.sql file at the root of schema to it, in lexical order. schema may be
+// nil. The database is closed when t finishes.
+//
+// The database is limited to a single connection, which is never closed
+// while idle, as every connection to ":memory:" gets a database of its own
+// and the database is gone once its connection closes. Rows must therefore be
+// closed before running further statements.
+func NewSQLite(t testing.TB, schema fs.FS) *sql.DB {
+	t.Helper()
+
+	db, er := sql.Open("sqlite3", ":memory:")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	t.Cleanup(func() { db.Close() })
+
+	if schema == nil {
+		return db
+	}
+
+	paths, er := fs.Glob(schema, "*.sql")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		bs, er := fs.ReadFile(schema, path)
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if _, er := db.Exec(string(bs)); er != nil {
+			t.Fatalf("crudtest: applying %s: %v", path, er)
+		}
+	}
+
+	return db
+}
@@ -0,0 +1,120 @@
+package crudtest_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	crud "github.com/lye/crud2"
+	"github.com/lye/crud2/crudtest"
+)
+
+type Gadget struct {
+	Id     int64      `crud:"gadget_id"`
+	Name   string     `crud:"gadget_name"`
+	Weight float64    `crud:"gadget_weight"`
+	Tags   []string   `crud:"gadget_tags,json"`
+	Seen   *time.Time `crud:"gadget_seen"`
+
+	hooked bool
+}
+
+func (g *Gadget) BeforeInsert(ctx context.Context, db crud.DbIsh) error {
+	g.hooked = true
+	return nil
+}
+
+var testFS = fstest.MapFS{
+	"schema/001_gadget.sql": {Data: []byte(`
+		CREATE TABLE gadget
+			( gadget_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, gadget_name TEXT NOT NULL
+			, gadget_weight REAL NOT NULL DEFAULT 0
+			, gadget_tags TEXT
+			, gadget_seen TIMESTAMP
+			);
+	`)},
+	"schema/002_widget.sql": {Data: []byte(`
+		CREATE TABLE widget
+			( widget_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, widget_name TEXT NOT NULL
+			, widget_count INTEGER NOT NULL
+			);
+	`)},
+	"schema/README": {Data: []byte("not a schema file")},
+	"fixtures/gadgets.yaml": {Data: []byte(`
+- gadget_name: sprocket
+  gadget_weight: 1.5
+  gadget_tags: [small, metal]
+  gadget_seen: 2020-01-02T03:04:05Z
+- gadget_name: gear
+  gadget_weight: 3
+`)},
+	"fixtures/widgets.json": {Data: []byte(`[
+		{"widget_name": "a", "widget_count": 1},
+		{"widget_name": "b", "widget_count": 9007199254740993}
+	]`)},
+}
+
+func TestNewSQLite(t *testing.T) {
+	schema, er := testFS.Sub("schema")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	db := crudtest.NewSQLite(t, schema)
+
+	gadgets := crudtest.LoadFixtures[Gadget](t, db, testFS, "fixtures/gadgets.yaml", "gadget", "gadget_id")
+
+	if len(gadgets) != 2 || gadgets[0].Id == 0 || gadgets[1].Id == gadgets[0].Id {
+		t.Fatalf("Expected fixtures to be inserted with ids: %#v", gadgets)
+	}
+
+	if !gadgets[0].hooked || !gadgets[1].hooked {
+		t.Errorf("Expected fixtures to be inserted through crud.Insert hooks")
+	}
+
+	loaded := []Gadget{}
+
+	if er := crud.SelectWhere(db, "gadget", &loaded, "gadget_name = $1", "sprocket"); er != nil {
+		t.Fatal(er)
+	}
+
+	seen := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if len(loaded) != 1 {
+		t.Fatalf("Expected 1 sprocket, got %d", len(loaded))
+	}
+
+	g := loaded[0]
+
+	if g.Weight != 1.5 || len(g.Tags) != 2 || g.Tags[1] != "metal" || g.Seen == nil || !g.Seen.Equal(seen) {
+		t.Errorf("Fixture not loaded correctly: %#v", g)
+	}
+
+	widgets := crudtest.LoadFixtures[Widget](t, db, testFS, "fixtures/widgets.json", "widget", "widget_id")
+
+	if len(widgets) != 2 || widgets[1].Count != 9007199254740993 {
+		t.Errorf("JSON fixtures not loaded correctly: %#v", widgets)
+	}
+}
+
+func TestNewSQLiteIsolated(t *testing.T) {
+	schema, er := testFS.Sub("schema")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	db := crudtest.NewSQLite(t, schema)
+
+	var count int
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM gadget").Scan(&count); er != nil {
+		t.Fatal(er)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected a fresh database, found %d gadgets", count)
+	}
+}
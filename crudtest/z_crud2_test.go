@@ -0,0 +1,120 @@
+package crudtest_test
+
+// AUTOGENERATED CODE. Regenerate by running crudgen.
+
+import (
+	crud "github.com/lye/crud2"
+	time "time"
+)
+
+func (self *Gadget) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "gadget_id":
+			values[i] = &self.Id
+
+		case "gadget_name":
+			values[i] = &self.Name
+
+		case "gadget_seen":
+			values[i] = &self.Seen
+
+		case "gadget_tags":
+			values[i] = crud.JSON(&self.Tags)
+
+		case "gadget_weight":
+			values[i] = &self.Weight
+
+		}
+	}
+}
+
+func (self *Gadget) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 5)
+	values = make([]interface{}, 0, 5)
+
+	names = append(names, "gadget_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "gadget_name")
+	values = append(values, &self.Name)
+
+	names = append(names, "gadget_seen")
+	values = append(values, &self.Seen)
+
+	names = append(names, "gadget_tags")
+	values = append(values, crud.JSON(&self.Tags))
+
+	names = append(names, "gadget_weight")
+	values = append(values, &self.Weight)
+
+	return
+}
+
+func (self *Gadget) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "json":
+		return []string{"gadget_tags"}
+
+	}
+
+	return nil
+}
+
+func (self *Gadget) Clone() crud.FieldBinder {
+	clone := new(Gadget)
+	*clone = *self
+	if self.Seen != nil {
+		p1 := new(time.Time)
+		*p1 = *self.Seen
+		clone.Seen = p1
+	}
+	if self.Tags != nil {
+		clone.Tags = make([]string, len(self.Tags))
+		copy(clone.Tags, self.Tags)
+	}
+
+	return clone
+}
+
+func (self *Widget) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "widget_count":
+			values[i] = &self.Count
+
+		case "widget_id":
+			values[i] = &self.Id
+
+		case "widget_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *Widget) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 3)
+	values = make([]interface{}, 0, 3)
+
+	names = append(names, "widget_count")
+	values = append(values, &self.Count)
+
+	names = append(names, "widget_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "widget_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *Widget) Clone() crud.FieldBinder {
+	clone := new(Widget)
+	*clone = *self
+
+	return clone
+}
@@ -3,6 +3,7 @@ package crud
 import (
 	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
@@ -20,10 +21,14 @@ type Dialect interface {
 	Update(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error
 }
 
-func genericScan(rows *sql.Rows, args ...FieldBinder) error {
+// bindRow builds the slice of destinations for rows.Scan by asking each
+// FieldBinder to claim the columns of rows. Columns that nobody claims are
+// scanned into throwaway values, and destinations with a registered
+// converter are wrapped to use it.
+func bindRow(rows *sql.Rows, args ...FieldBinder) ([]interface{}, error) {
 	columns, er := rows.Columns()
 	if er != nil {
-		return er
+		return nil, er
 	}
 
 	// Force the column names to all be lower-case. This pre-emptively works
@@ -44,6 +49,17 @@ func genericScan(rows *sql.Rows, args ...FieldBinder) error {
 		}
 	}
 
+	convertDests(values)
+
+	return values, nil
+}
+
+func genericScan(rows *sql.Rows, args ...FieldBinder) error {
+	values, er := bindRow(rows, args...)
+	if er != nil {
+		return er
+	}
+
 	if er := rows.Scan(values...); er != nil {
 		return er
 	}
@@ -57,85 +73,388 @@ func genericScan(rows *sql.Rows, args ...FieldBinder) error {
 	return nil
 }
 
-func genericInsert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (int64, error) {
+// insertQuery holds the parts of an INSERT statement built from a
+// FieldEnumerator, leaving out the id field so it can be assigned by the
+// database.
+type insertQuery struct {
+	table  string
+	id     string
+	fields []string
+	values []interface{}
+
+	// defaults lists the `,dbdefault` columns, which are also left out so
+	// that the database can fill them in.
+	defaults []string
+
+	// restore undoes the changes to obj's timestamp columns, if the
+	// statement fails.
+	restore func()
+}
+
+func buildInsert(table, sqlIdFieldName string, obj FieldEnumerator) (ins *insertQuery, er error) {
 	if er := deflate(obj); er != nil {
-		return 0, er
+		return nil, er
 	}
 
 	objFields, objValues := obj.EnumerateFields()
 
 	if len(objFields) != len(objValues) {
-		return 0, ErrLengthMismatch
+		return nil, ErrLengthMismatch
 	}
 
-	sqlFields := make([]string, 0, len(objFields))
-	sqlValues := make([]interface{}, 0, len(objFields))
-	placeholders := make([]string, 0, len(objFields))
+	restore, er := touchTimestamps(obj, objFields, objValues, "created", "updated")
+	if er != nil {
+		return nil, er
+	}
+
+	defer func() {
+		if er != nil {
+			restore()
+		}
+	}()
+
+	ins = &insertQuery{
+		table:    table,
+		id:       sqlIdFieldName,
+		fields:   make([]string, 0, len(objFields)),
+		values:   make([]interface{}, 0, len(objFields)),
+		defaults: flaggedFields(obj, "dbdefault"),
+		restore:  restore,
+	}
 
 	for i, field := range objFields {
 		// If there's an id field, skip it so it can be automatically assigned.
-		if field != sqlIdFieldName {
-			sqlValues = append(sqlValues, objValues[i])
-			sqlFields = append(sqlFields, field)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", len(sqlValues)))
+		if field != sqlIdFieldName && !contains(ins.defaults, field) {
+			ins.values = append(ins.values, objValues[i])
+			ins.fields = append(ins.fields, field)
 		}
 	}
 
+	if ins.values, er = convertArgs(ins.values); er != nil {
+		return nil, er
+	}
+
+	wrapSecrets(obj, ins.fields, ins.values)
+
+	return ins, nil
+}
+
+// String renders the statement, with a RETURNING clause if returning is
+// non-empty.
+func (ins *insertQuery) String(returning []string) string {
+	placeholders := make([]string, len(ins.values))
+
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
 	q := `
 		INSERT INTO %s 
 		(%s)
 		VALUES (%s)
 	`
-	q = fmt.Sprintf(q, table, strings.Join(sqlFields, ", "), strings.Join(placeholders, ", "))
+	q = fmt.Sprintf(q, ins.table, strings.Join(ins.fields, ", "), strings.Join(placeholders, ", "))
+
+	if len(returning) > 0 {
+		q += "RETURNING " + strings.Join(returning, ", ")
+	}
+
+	return q
+}
 
-	res, er := db.Exec(q, sqlValues...)
+// insertReturning runs ins with a RETURNING clause for the id and the
+// `,dbdefault` columns, which are scanned back into obj. If obj binds the id
+// column it is scanned there too; the id is also returned if it is an
+// integer.
+func insertReturning(db DbIsh, ins *insertQuery, args []interface{}, obj FieldEnumerator) (int64, error) {
+	var id int64
+	var idDest *int64
+
+	returning := ins.defaults
+	key := bindPrimaryKey(obj, ins.id)
+
+	if ins.id != "" {
+		returning = append([]string{ins.id}, ins.defaults...)
+
+		if key == nil {
+			idDest = &id
+		}
+	}
+
+	found, er := scanReturning(db, ins.String(returning), args, obj, idDest)
 	if er != nil {
 		return 0, er
 	}
 
-	return res.LastInsertId()
+	if !found {
+		return 0, sql.ErrNoRows
+	}
+
+	if key != nil {
+		id, _ = integerKey(key)
+	}
+
+	return id, nil
 }
 
-func genericUpdate(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+func genericInsert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (id int64, er error) {
+	ins, er := buildInsert(table, sqlIdFieldName, obj)
+	if er != nil {
+		return 0, er
+	}
+
+	defer func() {
+		if er != nil {
+			ins.restore()
+		}
+	}()
+
+	key := bindPrimaryKey(obj, ins.id)
+
+	// RETURNING requires SQLite 3.35, so only use it when it's needed: for
+	// `,dbdefault` columns, and for keys that LastInsertId can't provide.
+	if _, ok := integerKey(key); len(ins.defaults) > 0 || (key != nil && !ok) {
+		return insertReturning(db, ins, ins.values, obj)
+	}
+
+	res, er := db.Exec(ins.String(nil), ins.values...)
+	if er != nil {
+		return 0, er
+	}
+
+	id, er = res.LastInsertId()
+	if er != nil {
+		return 0, er
+	}
+
+	if key != nil {
+		if er := setIntegerKey(key, id); er != nil {
+			return 0, er
+		}
+	}
+
+	return id, nil
+}
+
+// bindPrimaryKey returns the destination obj binds for the id column, or nil
+// if there is no id column or obj doesn't bind it.
+func bindPrimaryKey(obj interface{}, sqlIdFieldName string) interface{} {
+	binder, ok := obj.(FieldBinder)
+	if !ok || sqlIdFieldName == "" {
+		return nil
+	}
+
+	values := make([]interface{}, 1)
+	binder.BindFields([]string{strings.ToLower(sqlIdFieldName)}, values)
+
+	return values[0]
+}
+
+// integerKey returns the value key points to if it is an integer type, such
+// as int64 or int32. ok is false for other keys, such as strings and UUIDs.
+func integerKey(key interface{}) (id int64, ok bool) {
+	v := reflect.ValueOf(key)
+
+	if v.Kind() != reflect.Pointer || v.IsNil() || lookupConverter(v.Type().Elem()) != nil {
+		return 0, false
+	}
+
+	switch v = v.Elem(); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+
+	return 0, false
+}
+
+// setIntegerKey stores id into the integer key points to.
+func setIntegerKey(key interface{}, id int64) error {
+	v := reflect.ValueOf(key).Elem()
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(id) {
+			return fmt.Errorf("crud2: generated id %d overflows %s", id, v.Type())
+		}
+		v.SetInt(id)
+
+	default:
+		if id < 0 || v.OverflowUint(uint64(id)) {
+			return fmt.Errorf("crud2: generated id %d overflows %s", id, v.Type())
+		}
+		v.SetUint(uint64(id))
+	}
+
+	return nil
+}
+
+// updateQuery holds the parts of an UPDATE statement built from a
+// FieldEnumerator. The id field is used as the WHERE constraint.
+type updateQuery struct {
+	table  string
+	id     string
+	fields []string
+	values []interface{}
+
+	// defaults lists the `,dbdefault` columns, which are left out of the SET
+	// expression and read back instead.
+	defaults []string
+
+	// restore undoes the changes to obj's timestamp columns, if the
+	// statement fails.
+	restore func()
+}
+
+func buildUpdate(table, sqlIdFieldName string, obj FieldEnumerator) (upd *updateQuery, er error) {
 	if er := deflate(obj); er != nil {
-		return er
+		return nil, er
 	}
 
 	objFields, objValues := obj.EnumerateFields()
 
 	if len(objFields) != len(objValues) {
-		return ErrLengthMismatch
+		return nil, ErrLengthMismatch
 	}
 
-	sqlFields := make([]string, 0, len(objFields))
-	sqlValues := make([]interface{}, 0, len(objFields))
+	restore, er := touchTimestamps(obj, objFields, objValues, "updated")
+	if er != nil {
+		return nil, er
+	}
+
+	defer func() {
+		if er != nil {
+			restore()
+		}
+	}()
+
+	created := flaggedFields(obj, "created")
+
+	upd = &updateQuery{
+		table:    table,
+		id:       sqlIdFieldName,
+		fields:   make([]string, 0, len(objFields)),
+		values:   make([]interface{}, 0, len(objFields)),
+		defaults: flaggedFields(obj, "dbdefault"),
+		restore:  restore,
+	}
 
 	var idValue interface{} = nil
 
 	for i, field := range objFields {
-		// Yank the id field out of the SET expression so it can be used as a WHERE constraint.
+		// Yank the id field out of the SET expression so it can be used as a
+		// WHERE constraint. `,created` columns are never overwritten.
 		if field == sqlIdFieldName {
 			idValue = objValues[i]
 
-		} else {
-			sqlValues = append(sqlValues, objValues[i])
-			sqlFields = append(sqlFields, fmt.Sprintf("%s = $%d", field, len(sqlValues)))
+		} else if !contains(upd.defaults, field) && !contains(created, field) {
+			upd.values = append(upd.values, objValues[i])
+			upd.fields = append(upd.fields, field)
 		}
 	}
 
 	if idValue == nil {
-		return ErrUnsetPKey
+		return nil, ErrUnsetPKey
+	}
+
+	if len(upd.fields) == 0 {
+		return nil, ErrNoColumns
 	}
 
-	sqlValues = append(sqlValues, idValue)
+	upd.values = append(upd.values, idValue)
+
+	if upd.values, er = convertArgs(upd.values); er != nil {
+		return nil, er
+	}
+
+	wrapSecrets(obj, upd.fields, upd.values)
+
+	return upd, nil
+}
+
+// String renders the statement, reading back the `,dbdefault` columns with
+// a RETURNING clause if there are any.
+func (upd *updateQuery) String() string {
+	sets := make([]string, len(upd.fields))
+
+	for i, field := range upd.fields {
+		sets[i] = fmt.Sprintf("%s = $%d", field, i+1)
+	}
 
 	q := `
 		UPDATE %s
 		SET %s
 		WHERE %s = $%d
 	`
-	q = fmt.Sprintf(q, table, strings.Join(sqlFields, ", "), sqlIdFieldName, len(sqlValues))
+	q = fmt.Sprintf(q, upd.table, strings.Join(sets, ", "), upd.id, len(upd.values))
 
-	_, er := db.Exec(q, sqlValues...)
+	if len(upd.defaults) > 0 {
+		q += "RETURNING " + strings.Join(upd.defaults, ", ")
+	}
+
+	return q
+}
+
+// execUpdate runs upd with args, scanning any `,dbdefault` columns back into
+// obj.
+func execUpdate(db DbIsh, upd *updateQuery, args []interface{}, obj FieldEnumerator) error {
+	if len(upd.defaults) > 0 {
+		_, er := scanReturning(db, upd.String(), args, obj, nil)
+		return er
+	}
+
+	_, er := db.Exec(upd.String(), args...)
 	return er
 }
+
+func genericUpdate(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	upd, er := buildUpdate(table, sqlIdFieldName, obj)
+	if er != nil {
+		return er
+	}
+
+	if er := execUpdate(db, upd, upd.values, obj); er != nil {
+		upd.restore()
+		return er
+	}
+
+	return nil
+}
+
+// scanReturning runs q, which ends in a RETURNING clause, and scans the row
+// it returns into obj through BindFields. If id is non-nil, the first column
+// is scanned into it instead. found is false if no row was returned.
+func scanReturning(db DbIsh, q string, args []interface{}, obj FieldEnumerator, id *int64) (found bool, er error) {
+	rows, er := db.Query(q, args...)
+	if er != nil {
+		return false, er
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+
+	binders := []FieldBinder{}
+
+	if binder, ok := obj.(FieldBinder); ok {
+		binders = append(binders, binder)
+	}
+
+	values, er := bindRow(rows, binders...)
+	if er != nil {
+		return false, er
+	}
+
+	if id != nil {
+		values[0] = id
+	}
+
+	if er := rows.Scan(values...); er != nil {
+		return false, er
+	}
+
+	return true, rows.Close()
+}
@@ -2,74 +2,81 @@ package crud
 
 import (
 	"database/sql"
-	"fmt"
-	"strings"
+	"errors"
 )
 
+// PostgresDialect implements Dialect for PostgreSQL. Array columns are
+// written using PostgreSQL's array literal syntax.
 type PostgresDialect struct{}
 
 func (PostgresDialect) Scan(rows *sql.Rows, args ...FieldBinder) error {
 	return genericScan(rows, args...)
 }
 
-func (PostgresDialect) Insert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (id int64, er error) {
-	if er := deflate(obj); er != nil {
-		return 0, er
-	}
+func (PostgresDialect) Insert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (int64, error) {
+	return hookInsert(db, obj, func() (int64, error) {
+		return postgresInsert(db, table, sqlIdFieldName, obj)
+	})
+}
 
-	objFields, objValues := obj.EnumerateFields()
+func (PostgresDialect) Update(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	return hookUpdate(db, obj, func() error {
+		return postgresUpdate(db, table, sqlIdFieldName, obj)
+	})
+}
 
-	if len(objFields) != len(objValues) {
-		panic("crud2: FieldEnumerator.EnumerateFields' return values must have same length")
+// IsRetryable implements RetryClassifier, reporting serialization failures
+// (SQLSTATE 40001) and deadlocks (40P01) as retryable. It understands errors
+// with an SQLState method, as provided by lib/pq and pgx.
+func (PostgresDialect) IsRetryable(er error) bool {
+	var state interface {
+		SQLState() string
 	}
 
-	sqlFields := make([]string, 0, len(objFields))
-	sqlValues := make([]interface{}, 0, len(objFields))
-	placeholders := make([]string, 0, len(objFields))
+	if !errors.As(er, &state) {
+		return false
+	}
 
-	for i, field := range objFields {
-		// If there's an id field, skip it so it can be automatically assigned.
-		if field != sqlIdFieldName {
-			sqlValues = append(sqlValues, objValues[i])
-			sqlFields = append(sqlFields, field)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", len(sqlValues)))
-		}
+	switch state.SQLState() {
+	case "40001", "40P01":
+		return true
 	}
 
-	var q string
+	return false
+}
 
-	if sqlIdFieldName != "" {
-		q = `
-			INSERT INTO %s 
-			(%s)
-			VALUES (%s)
-			RETURNING %s
-		`
-		q = fmt.Sprintf(q, table, strings.Join(sqlFields, ", "), strings.Join(placeholders, ", "), sqlIdFieldName)
+func postgresInsert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (id int64, er error) {
+	ins, er := buildInsert(table, sqlIdFieldName, obj)
+	if er != nil {
+		return 0, er
+	}
 
-		rows, er := db.Query(q, sqlValues...)
+	defer func() {
 		if er != nil {
-			return 0, er
+			ins.restore()
 		}
-		defer rows.Close()
-
-		rows.Next()
-		er = rows.Scan(&id)
+	}()
 
-	} else {
-		q = `
-			INSERT INTO %s 
-			(%s)
-			VALUES (%s)
-		`
-		q = fmt.Sprintf(q, table, strings.Join(sqlFields, ", "), strings.Join(placeholders, ", "))
+	args := postgresValues(ins.values)
 
-		_, er = db.Exec(q, sqlValues...)
+	if sqlIdFieldName == "" && len(ins.defaults) == 0 {
+		_, er = db.Exec(ins.String(nil), args...)
+		return 0, er
 	}
 
-	return
+	return insertReturning(db, ins, args, obj)
 }
 
-func (PostgresDialect) Update(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
-	return genericUpdate(db, table, sqlIdFieldName, obj)
+func postgresUpdate(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	upd, er := buildUpdate(table, sqlIdFieldName, obj)
+	if er != nil {
+		return er
+	}
+
+	if er := execUpdate(db, upd, postgresValues(upd.values), obj); er != nil {
+		upd.restore()
+		return er
+	}
+
+	return nil
 }
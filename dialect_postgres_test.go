@@ -0,0 +1,107 @@
+package crud
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestPostgresArrayLiteral(t *testing.T) {
+	strs := []string{"a", `b "c"`, `d\e`, "", "NULL"}
+
+	value, er := postgresArray{Array(&strs)}.Value()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	expected := `{"a","b \"c\"","d\\e","","NULL"}`
+
+	if value != expected {
+		t.Errorf("Encoding mismatch\ne: %s\na: %s", expected, value)
+	}
+
+	var out []string
+
+	if er := Array(&out).Scan([]byte(expected)); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(out) != len(strs) {
+		t.Fatalf("Decoding mismatch: %#v", out)
+	}
+
+	for i := range strs {
+		if out[i] != strs[i] {
+			t.Errorf("Decoding mismatch at %d: %q != %q", i, out[i], strs[i])
+		}
+	}
+
+	var ints []*int64
+
+	if er := Array(&ints).Scan("{1, NULL,-3}"); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(ints) != 3 || *ints[0] != 1 || ints[1] != nil || *ints[2] != -3 {
+		t.Errorf("Decoding mismatch: %#v", ints)
+	}
+
+	var plain []int64
+
+	if er := Array(&plain).Scan("{1,NULL}"); er == nil {
+		t.Errorf("Expected NULL element to fail to scan into []int64")
+	}
+
+	floats := []float64{1.5, math.Inf(-1)}
+
+	if value, er := (postgresArray{Array(&floats)}).Value(); er != nil || value != "{1.5,-Infinity}" {
+		t.Errorf("Encoding mismatch: %v (%v)", value, er)
+	}
+
+	var empty []float64
+
+	if er := Array(&empty).Scan("{}"); er != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("Decoding mismatch: %#v (%v)", empty, er)
+	}
+
+	if er := Array(&empty).Scan("{{1,2},{3,4}}"); er == nil {
+		t.Errorf("Expected multi-dimensional array to fail to scan")
+	}
+
+	var bounded []string
+
+	if er := Array(&bounded).Scan("[0:1]={a,b}"); er != nil || len(bounded) != 2 || bounded[0] != "a" || bounded[1] != "b" {
+		t.Errorf("Decoding mismatch for an array with bounds: %#v (%v)", bounded, er)
+	}
+
+	if er := Array(&bounded).Scan(`["[0:1]={a,b}"]`); er != nil || len(bounded) != 1 || bounded[0] != "[0:1]={a,b}" {
+		t.Errorf("Decoding mismatch for a JSON array: %#v (%v)", bounded, er)
+	}
+}
+
+type sqlStateError string
+
+func (er sqlStateError) Error() string {
+	return "pq: " + string(er)
+}
+
+func (er sqlStateError) SQLState() string {
+	return string(er)
+}
+
+func TestPostgresIsRetryable(t *testing.T) {
+	cases := map[error]bool{
+		sqlStateError("40001"):                            true,
+		sqlStateError("40P01"):                            true,
+		fmt.Errorf("wrapped: %w", sqlStateError("40001")): true,
+		sqlStateError("23505"):                            false,
+		fmt.Errorf("database is locked"):                  false,
+		nil:                                               false,
+	}
+
+	for er, expected := range cases {
+		if (PostgresDialect{}).IsRetryable(er) != expected {
+			t.Errorf("Expected IsRetryable(%v) to be %v", er, expected)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package crud
 
 import (
 	"database/sql"
+	"strings"
 )
 
 type SQLite3Dialect struct{}
@@ -11,9 +12,26 @@ func (SQLite3Dialect) Scan(rows *sql.Rows, args ...FieldBinder) error {
 }
 
 func (SQLite3Dialect) Insert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (int64, error) {
-	return genericInsert(db, table, sqlIdFieldName, obj)
+	return hookInsert(db, obj, func() (int64, error) {
+		return genericInsert(db, table, sqlIdFieldName, obj)
+	})
 }
 
 func (SQLite3Dialect) Update(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
-	return genericUpdate(db, table, sqlIdFieldName, obj)
+	return hookUpdate(db, obj, func() error {
+		return genericUpdate(db, table, sqlIdFieldName, obj)
+	})
+}
+
+// IsRetryable implements RetryClassifier, reporting SQLITE_BUSY and
+// SQLITE_LOCKED errors as retryable. They are recognised by their messages so
+// that this package doesn't depend on a particular driver.
+func (SQLite3Dialect) IsRetryable(er error) bool {
+	if er == nil {
+		return false
+	}
+
+	msg := er.Error()
+
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
 }
@@ -54,11 +54,213 @@ noisy code increases significantly. crud provides the following alternative:
 	foos := []Foos{}
 	crud.ScanAll(rows, &foos)
 
+With generics, the same can be written without reflection, and with the
+element type checked at compile time. First and One return a single row, or
+ErrNotFound:
+
+	foos, er := crud.Select[Foo](ctx, db, "SELECT * FROM foos WHERE foo_num > $1", 10)
+	foo, er := crud.One[Foo](ctx, db, "SELECT * FROM foos WHERE foo_str = $1", "etc")
+
+Large result sets can be streamed with Iterate, which scans one row at a time
+and closes rows when the loop ends:
+
+	for foo, er := range crud.Iterate[Foo](rows) {
+		...
+	}
+
+Page reads a table one page at a time using keyset pagination, which stays
+fast on large tables where OFFSET doesn't. It returns an opaque Cursor for the
+next page, which is empty after the last one:
+
+	foos := []Foo{}
+	next, er := crud.Page(db, nil, "foo", &foos, []string{"foo_time", "foo_id"}, cursor, 50)
+
 Each struct field that has a corresponding SQL row must be tagged with the SQL 
 row name. For time types, the "unix" tag can be used to trigger marshalling between
 the Go time.Time type and a numeric SQL field. 
 
+Insert leaves the primary key column out of the INSERT so that the database
+can assign it, then writes the new key back into the struct's field:
+
+	foo := &Foo{Num: 1}
+	crud.Insert(db, "foo", "foo_id", foo) // foo.Id is now set
+
+Keys of any integer type are also returned. Other keys, such as strings or
+UUIDs filled in by a DEFAULT clause, are read back with RETURNING.
+
+Get loads a single row by its primary key, returning ErrNotFound if there is
+none, and Reload refreshes a struct from the database using the key it holds:
+
+	foo := &Foo{}
+	er := crud.Get(db, "foo", "foo_id", 1, foo)
+	er = crud.Reload(db, "foo", "foo_id", foo)
+
 Any pointer fields with a corresponding sql.Null* type are marshalled to/from 
 the Null type for proper interaction with database/sql.
+
+Fields tagged with ",json" are stored as JSON documents (e.g. TEXT in SQLite or
+JSONB in PostgreSQL) and can be of any type that encoding/json understands.
+NULL columns scan as nil into pointer, map and slice fields, and nil values
+are written as NULL:
+
+	Settings map[string]string `crud:"foo_settings,json"`
+
+Fields of type []string, []int64 or []float64 (or slices of pointers to them,
+which allow NULL elements) can be tagged with ",array". PostgresDialect stores
+them as native arrays; SQLite3Dialect stores them as JSON arrays:
+
+	Tags []string `crud:"foo_tags,array"`
+
+Fields whose types implement encoding.TextMarshaler and
+encoding.TextUnmarshaler, such as enums, can be tagged with ",text" to be
+stored as their text representation. Values that fail to unmarshal cause Scan
+to return an error naming the column:
+
+	Status Status `crud:"foo_status,text"`
+
+Fields tagged with ",nullzero" store their zero value (e.g. the empty string
+or the zero time.Time) as NULL, and scan NULL back into the zero value. This
+avoids pointers for nullable columns where the zero value means "absent":
+
+	Nickname string `crud:"foo_nickname,nullzero"`
+
+Fields tagged with ",dbdefault" are filled in by the database: they are left
+out of the columns written by Insert and Update, and are read back into the
+struct with RETURNING on PostgreSQL and SQLite 3.35 or later. This is useful
+for columns populated by DEFAULT clauses or triggers:
+
+	CreatedAt time.Time `crud:"foo_created_at,dbdefault"`
+
+Fields tagged with ",created" and ",updated" are maintained automatically.
+Insert sets both to the current time, while Update only refreshes ",updated"
+columns and leaves ",created" ones out of the UPDATE entirely. The clock is
+read from crud.Now, which tests can replace:
+
+	CreatedAt time.Time `crud:"foo_created_at,created"`
+	UpdatedAt time.Time `crud:"foo_updated_at,updated"`
+
+A *time.Time field tagged with ",deleted" enables soft deletes. Delete sets
+it to the current time and saves the struct instead of removing the row,
+Restore clears it again, and SelectWhere and the generated fetch helpers
+leave soft-deleted rows out. Wrapping the DbIsh with Unscoped includes them,
+and makes Delete remove rows outright:
+
+	Deleted *time.Time `crud:"foo_deleted_at,deleted"`
+
+	crud.Delete(db, "foo", "foo_id", foo)
+	crud.SelectWhere(crud.Unscoped(db), "foo", &foos, "foo_num > $1", 10)
+
+Relationships between tables are declared on slice and pointer fields, then
+loaded for many structs at once with Preload, which reads all of the related
+rows with one IN query instead of one query per struct. A ",hasmany" field
+names the related table and its foreign key column, which refers to the
+struct's ",pk" column; a ",belongsto" field names its own foreign key column
+and the related table's key:
+
+	type Customer struct {
+		Id     int64    `crud:"customer_id,pk"`
+		Orders []*Order `crud:",hasmany=orders.order_customer_id"`
+	}
+
+	type Order struct {
+		CustomerId int64     `crud:"order_customer_id"`
+		Customer   *Customer `crud:"order_customer_id,belongsto=customer.customer_id"`
+	}
+
+	crud.Preload(db, customers, "Orders")
+
+Many-to-many relationships go through a join table with one column named
+after the ",pk" column of each side. A ",manytomany" field names the join
+table and the related table, and is loaded by Preload with a single JOIN.
+Associate and Dissociate add and remove links between two structs, and
+ReplaceAssociations sets the complete list of links of one struct in a
+transaction:
+
+	type Post struct {
+		Id   int64  `crud:"post_id,pk"`
+		Tags []*Tag `crud:",manytomany=post_tag.tag"`
+	}
+
+	crud.Associate(db, post, tag, "post_tag")
+	crud.ReplaceAssociations(db, post, tags, "post_tag")
+	crud.Preload(db, posts, "Tags")
+
+Types that can't be tagged, such as those from third-party packages, can be
+mapped with RegisterConverter. Converters are consulted for every value passed
+to Insert and Update and every destination bound by Scan:
+
+	crud.RegisterConverter(func(id uuid.UUID) (driver.Value, error) {
+		return id.String(), nil
+	}, func(src interface{}) (uuid.UUID, error) {
+		return uuid.Parse(fmt.Sprint(src))
+	})
+
+Struct fields tagged with ",recurse" have their own tagged fields flattened
+into the parent. When such a field is a pointer, it is left nil after a Scan
+in which all of its columns were NULL (as happens with an unmatched LEFT JOIN),
+and all of its columns are written as NULL while it is nil:
+
+	type Pet struct {
+		Id    int64  `crud:"pet_id"`
+		Owner *Owner `crud:",recurse"`
+	}
+
+Structs can hook into writes by implementing BeforeInserter, AfterInserter,
+BeforeUpdater, AfterUpdater, BeforeDeleter or AfterDeleter. Hooks receive the
+DbIsh the write was made through, so they can make further writes in the same
+transaction, and the context it was wrapped with by WithContext. An error from
+a Before hook aborts the write:
+
+	func (foo *Foo) AfterUpdate(ctx context.Context, db crud.DbIsh) error {
+		_, er := db.Exec("INSERT INTO audit (foo_id) VALUES ($1)", foo.Id)
+		return er
+	}
+
+	crud.Update(crud.WithContext(ctx, tx), "foo", "foo_id", foo)
+
+WithTx runs a function in a transaction, committing it if the function
+succeeds and rolling it back if it fails or panics. Transactions that fail
+because of contention (SQLITE_BUSY and SQLITE_LOCKED in SQLite, serialization
+failures and deadlocks in PostgreSQL) are retried with exponential backoff:
+
+	er := crud.WithTx(ctx, db, nil, func(tx crud.DbIsh) error {
+		return crud.Update(tx, "foo", "foo_id", foo)
+	})
+
+Transactions can be nested. Begin returns a Tx, which implements DbIsh; if
+the DbIsh passed to Begin (or WithTx) is already a transaction, a savepoint is
+started within it instead, and rolling that back only undoes the work done
+since:
+
+	tx, er := crud.Begin(db) // db may be an *sql.DB, *sql.Tx or crud.Tx
+	defer tx.Rollback()
+	...
+	er = tx.Commit()
+
+Instrument wraps a DbIsh to report every statement, with its operation kind,
+table, arguments, duration, rows affected and error, to an Observer.
+SlogObserver logs them with log/slog. Values of columns tagged ",secret" are
+wrapped in a SecretValue, which is logged as "[REDACTED]":
+
+	Password string `crud:"user_password,secret"`
+
+	idb := crud.Instrument(db, crud.SlogObserver(slog.Default()))
+	crud.Insert(idb, "users", "user_id", user)
+
+MeteredDialect wraps a Dialect to record a count and latency for every
+Insert, Update and hard Delete, broken down by table, operation and outcome,
+in a Metrics implementation. Rows scanned are counted one by one under their
+Go type name, as "scanrow". ExpvarMetrics publishes them through expvar:
+
+	crud.DefaultDialect = crud.MeteredDialect{
+		Dialect: crud.PostgresDialect{},
+		Metrics: crud.NewExpvarMetrics("crud"),
+	}
+
+The crudtest package provides a fake database/sql driver that records the
+statements crud runs and answers them with scripted rows and results, for
+unit tests that don't need a real database. It can also open isolated
+in-memory SQLite databases with a schema applied, and load YAML or JSON
+fixtures into them through crud.Insert.
 */
 package crud
@@ -1,10 +1,23 @@
 package crud
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrLengthMismatch = errors.New("crud2: FieldEnumerator.EnumerateFields' return values must have same length")
 	ErrUnsetPKey      = errors.New("crud2: FieldEnumerator.EnumerateFields did not return a field that matched sqlIdFieldName")
+
+	// ErrNoColumns is returned by Update when every column other than the
+	// primary key is `,dbdefault` or `,created`, leaving nothing to set.
+	ErrNoColumns = errors.New("crud2: no columns to update besides the primary key and ,dbdefault and ,created columns")
+
+	// ErrNotFound is returned when a single row was asked for and none
+	// matched. It wraps sql.ErrNoRows.
+	ErrNotFound = fmt.Errorf("crud2: no matching row: %w", sql.ErrNoRows)
+
+	// ErrTooManyRows is returned by One when more than one row matched.
+	ErrTooManyRows = errors.New("crud2: more than one matching row")
 )
@@ -0,0 +1,91 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Get reads the row of table whose pk column equals id into obj, returning
+// ErrNotFound if there is none. Only the columns obj enumerates are selected
+// if it implements FieldEnumerator; otherwise every column is. As with
+// SelectWhere, soft-deleted rows are treated as missing unless db is
+// Unscoped.
+func Get(db DbIsh, table, pk string, id interface{}, obj FieldBinder) error {
+	columns := "*"
+
+	if enumerator, ok := obj.(FieldEnumerator); ok {
+		if fields, _ := enumerator.EnumerateFields(); len(fields) > 0 {
+			columns = strings.Join(fields, ", ")
+		}
+	}
+
+	id, er := convertArg(id)
+	if er != nil {
+		return er
+	}
+
+	q := selectQuery(db, columns, table, obj, fmt.Sprintf("%s = $1", pk))
+
+	rows, er := db.Query(q, id)
+	if er != nil {
+		return er
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if er := rows.Err(); er != nil {
+			return er
+		}
+
+		return ErrNotFound
+	}
+
+	if er := Scan(rows, obj); er != nil {
+		return er
+	}
+
+	return rows.Close()
+}
+
+// Reload re-reads obj from table in place, using the current value of its pk
+// column. It returns ErrNotFound if the row no longer exists.
+func Reload(db DbIsh, table, pk string, obj FieldEnumerator) error {
+	binder, ok := obj.(FieldBinder)
+	if !ok {
+		return fmt.Errorf("crud2: %T does not implement FieldBinder", obj)
+	}
+
+	id, found, er := columnValue(obj, pk)
+	if er != nil {
+		return er
+	}
+
+	if !found {
+		return ErrUnsetPKey
+	}
+
+	return Get(db, table, pk, id, binder)
+}
+
+// columnValue returns the value obj enumerates for column, converted to a
+// driver.Value. Converting dereferences it, so the result doesn't change if
+// the field is scanned into later.
+func columnValue(obj FieldEnumerator, column string) (value interface{}, found bool, er error) {
+	fields, values := obj.EnumerateFields()
+
+	for i, field := range fields {
+		if field != column {
+			continue
+		}
+
+		if value, er = convertArg(values[i]); er != nil {
+			return nil, true, er
+		}
+
+		value, er = driver.DefaultParameterConverter.ConvertValue(value)
+		return value, true, er
+	}
+
+	return nil, false, nil
+}
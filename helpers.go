@@ -16,6 +16,14 @@ func Scan(rows *sql.Rows, args ...FieldBinder) error {
 	return DefaultDialect.Scan(rows, args...)
 }
 
+// ScanAll scans every remaining row of rows into a new element appended to
+// the slice pointed to by slicePtr, then closes rows. The slice's elements
+// must be structs whose pointers implement FieldBinder.
+//
+// If the element type also implements Cloner, and DefaultDialect is one of
+// the built-in dialects, the columns are bound once and every row is cloned
+// out of the same scratch object instead. Other dialects, including wrappers
+// such as MeteredDialect, have every row passed to their Scan.
 func ScanAll(rows *sql.Rows, slicePtr interface{}) error {
 	defer rows.Close()
 
@@ -31,6 +39,10 @@ func ScanAll(rows *sql.Rows, slicePtr interface{}) error {
 		return fmt.Errorf("Argument to crud.ScanAll must be a slice of structs")
 	}
 
+	if cloner, ok := reflect.New(elemType).Interface().(Cloner); ok && bindsOnce(DefaultDialect) {
+		return scanAllCloned(rows, sliceVal, cloner)
+	}
+
 	for rows.Next() {
 		newVal := reflect.New(elemType)
 
@@ -44,7 +56,47 @@ func ScanAll(rows *sql.Rows, slicePtr interface{}) error {
 	return nil
 }
 
+// bindsOnce reports whether dialect scans rows with genericScan, so that a
+// result set can be scanned by binding its columns once, rather than calling
+// dialect.Scan for every row, without changing behavior.
+func bindsOnce(dialect Dialect) bool {
+	switch dialect.(type) {
+	case SQLite3Dialect, PostgresDialect:
+		return true
+	}
+
+	return false
+}
+
+func scanAllCloned(rows *sql.Rows, sliceVal reflect.Value, scratch Cloner) error {
+	values, er := bindRow(rows, scratch.(FieldBinder))
+	if er != nil {
+		return er
+	}
+
+	for rows.Next() {
+		if er := rows.Scan(values...); er != nil {
+			return er
+		}
+
+		clone := scratch.Clone()
+
+		if er := inflate(clone); er != nil {
+			return er
+		}
+
+		sliceVal.Set(reflect.Append(sliceVal, reflect.ValueOf(clone).Elem()))
+	}
+
+	return nil
+}
+
 // Insert is shorthand for DefaultDialect.Insert.
+//
+// If obj binds the sqlIdFieldName column through BindFields, the key assigned
+// by the database is written back into it. Integer keys of any size are also
+// returned; other keys, such as strings or UUIDs populated by a DEFAULT
+// clause, are only written back, and 0 is returned.
 func Insert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (int64, error) {
 	return DefaultDialect.Insert(db, table, sqlIdFieldName, obj)
 }
@@ -54,6 +106,24 @@ func Update(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
 	return DefaultDialect.Update(db, table, sqlIdFieldName, obj)
 }
 
+func flaggedFields(val interface{}, flag string) []string {
+	if flagger, ok := val.(FieldFlagger); ok {
+		return flagger.FlaggedFields(flag)
+	}
+
+	return nil
+}
+
+func contains(list []string, str string) bool {
+	for _, elem := range list {
+		if elem == str {
+			return true
+		}
+	}
+
+	return false
+}
+
 func inflate(val interface{}) (er error) {
 	if inflater, ok := val.(Inflater); ok {
 		er = inflater.CrudInflate()
@@ -0,0 +1,107 @@
+package crud
+
+import (
+	"context"
+)
+
+// BeforeInserter is implemented by structs that need to run code before they
+// are inserted. db is the DbIsh passed to Insert, so hooks can write to the
+// same transaction; ctx is the context it was wrapped with by WithContext,
+// if any. Returning an error aborts the insert.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context, db DbIsh) error
+}
+
+// AfterInserter is implemented by structs that need to run code after they
+// are inserted. The struct's primary key and `,dbdefault` columns have been
+// read back by the time it is called. An error is returned from Insert, but
+// can't undo the insert unless db is a transaction that gets rolled back.
+type AfterInserter interface {
+	AfterInsert(ctx context.Context, db DbIsh) error
+}
+
+// BeforeUpdater is the Update counterpart of BeforeInserter.
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context, db DbIsh) error
+}
+
+// AfterUpdater is the Update counterpart of AfterInserter.
+type AfterUpdater interface {
+	AfterUpdate(ctx context.Context, db DbIsh) error
+}
+
+// BeforeDeleter is the Delete counterpart of BeforeInserter.
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context, db DbIsh) error
+}
+
+// AfterDeleter is the Delete counterpart of AfterInserter.
+type AfterDeleter interface {
+	AfterDelete(ctx context.Context, db DbIsh) error
+}
+
+// hookInsert runs insert between obj's BeforeInsert and AfterInsert hooks.
+func hookInsert(db DbIsh, obj interface{}, insert func() (int64, error)) (int64, error) {
+	ctx := contextOf(db)
+
+	if hook, ok := obj.(BeforeInserter); ok {
+		if er := hook.BeforeInsert(ctx, db); er != nil {
+			return 0, er
+		}
+	}
+
+	id, er := insert()
+	if er != nil {
+		return 0, er
+	}
+
+	if hook, ok := obj.(AfterInserter); ok {
+		if er := hook.AfterInsert(ctx, db); er != nil {
+			return id, er
+		}
+	}
+
+	return id, nil
+}
+
+// hookUpdate runs update between obj's BeforeUpdate and AfterUpdate hooks.
+func hookUpdate(db DbIsh, obj interface{}, update func() error) error {
+	ctx := contextOf(db)
+
+	if hook, ok := obj.(BeforeUpdater); ok {
+		if er := hook.BeforeUpdate(ctx, db); er != nil {
+			return er
+		}
+	}
+
+	if er := update(); er != nil {
+		return er
+	}
+
+	if hook, ok := obj.(AfterUpdater); ok {
+		return hook.AfterUpdate(ctx, db)
+	}
+
+	return nil
+}
+
+// hookDelete runs del between obj's BeforeDelete and AfterDelete hooks.
+func hookDelete(db DbIsh, obj interface{}, del func() error) error {
+	ctx := contextOf(db)
+
+	if hook, ok := obj.(BeforeDeleter); ok {
+		if er := hook.BeforeDelete(ctx, db); er != nil {
+			return er
+		}
+	}
+
+	if er := del(); er != nil {
+		return er
+	}
+
+	if hook, ok := obj.(AfterDeleter); ok {
+		return hook.AfterDelete(ctx, db)
+	}
+
+	return nil
+}
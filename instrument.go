@@ -0,0 +1,234 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Operation kinds reported in QueryEvent.Op.
+const (
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpScan   = "scan"
+	OpExec   = "exec"
+)
+
+// QueryEvent describes a statement run through an instrumented DbIsh.
+type QueryEvent struct {
+	// Op is the kind of statement: OpInsert, OpUpdate, OpDelete, OpScan for
+	// SELECTs, or OpExec for anything else.
+	Op string
+	// Table is the table the statement operates on, if it could be
+	// determined.
+	Table string
+	Query string
+	// Args are the statement's arguments. Values of `,secret` columns are
+	// wrapped in a SecretValue, which prints redacted.
+	Args     []interface{}
+	Duration time.Duration
+	// RowsAffected is reported for Exec, and is -1 for queries or if the
+	// driver doesn't support it.
+	RowsAffected int64
+	Err          error
+}
+
+// Observer receives a QueryEvent for each statement run through a DbIsh
+// returned by Instrument.
+type Observer interface {
+	ObserveQuery(ctx context.Context, event QueryEvent)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(ctx context.Context, event QueryEvent)
+
+func (fn ObserverFunc) ObserveQuery(ctx context.Context, event QueryEvent) {
+	fn(ctx, event)
+}
+
+type instrumentedDb struct {
+	DbIsh
+	observer Observer
+}
+
+// Instrument returns a DbIsh that reports every statement run against db to
+// observer. ctx is the one the statement runs with, as set by WithContext.
+//
+// The DbIsh WithTx passes its function is instrumented too, but a Tx
+// returned by Begin runs against db directly; wrap it with Instrument to
+// observe its statements.
+func Instrument(db DbIsh, observer Observer) DbIsh {
+	return instrumentedDb{db, observer}
+}
+
+func (db instrumentedDb) Unwrap() DbIsh {
+	return db.DbIsh
+}
+
+func (db instrumentedDb) observe(ctx context.Context, q string, args []interface{}, start time.Time, rowsAffected int64, er error) {
+	op, table := parseStatement(q)
+
+	db.observer.ObserveQuery(ctx, QueryEvent{
+		Op:           op,
+		Table:        table,
+		Query:        q,
+		Args:         args,
+		Duration:     time.Since(start),
+		RowsAffected: rowsAffected,
+		Err:          er,
+	})
+}
+
+func (db instrumentedDb) Exec(q string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(contextOf(db.DbIsh), q, args...)
+}
+
+func (db instrumentedDb) Prepare(q string) (*sql.Stmt, error) {
+	return db.PrepareContext(contextOf(db.DbIsh), q)
+}
+
+func (db instrumentedDb) Query(q string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(contextOf(db.DbIsh), q, args...)
+}
+
+// ExecContext, PrepareContext and QueryContext pass ctx on to db, so that
+// WithContext still applies when wrapped around Instrument, and report ctx to
+// the observer.
+func (db instrumentedDb) ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, er := execContext(ctx, db.DbIsh, q, args...)
+
+	rowsAffected := int64(-1)
+
+	if er == nil {
+		if n, er := res.RowsAffected(); er == nil {
+			rowsAffected = n
+		}
+	}
+
+	db.observe(ctx, q, args, start, rowsAffected, er)
+
+	return res, er
+}
+
+func (db instrumentedDb) PrepareContext(ctx context.Context, q string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, er := prepareContext(ctx, db.DbIsh, q)
+
+	db.observe(ctx, q, nil, start, -1, er)
+
+	return stmt, er
+}
+
+func (db instrumentedDb) QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, er := queryContext(ctx, db.DbIsh, q, args...)
+
+	db.observe(ctx, q, args, start, -1, er)
+
+	return rows, er
+}
+
+// parseStatement determines the operation kind and table of q from its
+// leading keywords.
+func parseStatement(q string) (op, table string) {
+	words := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ", ",", " , ").Replace(q))
+
+	if len(words) == 0 {
+		return OpExec, ""
+	}
+
+	after := func(keyword string) string {
+		for i, word := range words[:len(words)-1] {
+			if strings.EqualFold(word, keyword) {
+				return strings.Trim(words[i+1], `"`+"`")
+			}
+		}
+
+		return ""
+	}
+
+	switch strings.ToUpper(words[0]) {
+	case "INSERT":
+		return OpInsert, after("INTO")
+
+	case "UPDATE":
+		if len(words) > 1 {
+			table = strings.Trim(words[1], `"`+"`")
+		}
+
+		return OpUpdate, table
+
+	case "DELETE":
+		return OpDelete, after("FROM")
+
+	case "SELECT", "WITH":
+		return OpScan, after("FROM")
+	}
+
+	return OpExec, ""
+}
+
+// SlogObserver returns an Observer that logs each statement to logger, at
+// debug level, or error level if the statement failed. Arguments are logged
+// as the values passed to the driver, with `,secret` columns redacted.
+func SlogObserver(logger *slog.Logger) Observer {
+	return ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		level := slog.LevelDebug
+		if event.Err != nil {
+			level = slog.LevelError
+		}
+
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		args := make([]interface{}, len(event.Args))
+
+		for i, arg := range event.Args {
+			args[i] = displayArg(arg)
+		}
+
+		attrs := []slog.Attr{
+			slog.String("op", event.Op),
+			slog.String("table", event.Table),
+			slog.String("query", strings.Join(strings.Fields(event.Query), " ")),
+			slog.Int("num_args", len(args)),
+			slog.Any("args", args),
+			slog.Duration("duration", event.Duration),
+		}
+
+		if event.RowsAffected >= 0 {
+			attrs = append(attrs, slog.Int64("rows_affected", event.RowsAffected))
+		}
+
+		if event.Err != nil {
+			attrs = append(attrs, slog.Any("error", event.Err))
+		}
+
+		logger.LogAttrs(ctx, level, "crud query", attrs...)
+	})
+}
+
+// displayArg returns the value the driver will see for arg, so that pointers
+// to fields are logged as their values.
+func displayArg(arg interface{}) interface{} {
+	if secret, ok := arg.(SecretValue); ok {
+		return secret
+	}
+
+	value, er := driver.DefaultParameterConverter.ConvertValue(arg)
+	if er != nil {
+		return arg
+	}
+
+	if bs, ok := value.([]byte); ok {
+		return string(bs)
+	}
+
+	return value
+}
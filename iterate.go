@@ -0,0 +1,77 @@
+package crud
+
+import (
+	"database/sql"
+	"iter"
+)
+
+// Iterate returns an iterator over the rows of rows, each scanned into a new
+// T, for processing large result sets without holding them in memory:
+//
+//	for foo, er := range crud.Iterate[Foo](rows) {
+//		if er != nil {
+//			return er
+//		}
+//		...
+//	}
+//
+// As in ScanAll, if T implements Cloner and DefaultDialect is one of the
+// built-in dialects, the columns are bound once and every row is cloned out
+// of the same scratch object; otherwise every row is passed to
+// DefaultDialect.Scan. rows is closed when the iteration finishes, including
+// when the loop exits early. An error ends the iteration after being yielded
+// with a nil *T.
+func Iterate[T any, PT Scannable[T]](rows *sql.Rows) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		defer rows.Close()
+
+		scratch := PT(new(T))
+		cloner, cloned := interface{}(scratch).(Cloner)
+		cloned = cloned && bindsOnce(DefaultDialect)
+
+		var values []interface{}
+
+		if cloned {
+			var er error
+
+			if values, er = bindRow(rows, scratch); er != nil {
+				yield(nil, er)
+				return
+			}
+		}
+
+		for rows.Next() {
+			var obj PT
+
+			if cloned {
+				if er := rows.Scan(values...); er != nil {
+					yield(nil, er)
+					return
+				}
+
+				obj = cloner.Clone().(PT)
+
+				if er := inflate(obj); er != nil {
+					yield(nil, er)
+					return
+				}
+
+			} else {
+				obj = PT(new(T))
+
+				if er := Scan(rows, obj); er != nil {
+					yield(nil, er)
+					return
+				}
+			}
+
+			if !yield((*T)(obj), nil) {
+				return
+			}
+		}
+
+		if er := rows.Err(); er != nil {
+			yield(nil, er)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// JSONValue stores a Go value of any type as a JSON document, e.g. in a TEXT
+// column in SQLite or a JSONB column in PostgreSQL. It is bound by crudgen for
+// fields tagged with `,json`.
+type JSONValue struct {
+	ptr interface{}
+}
+
+// JSON wraps ptr, which must be a pointer, so that it is marshalled to JSON
+// when written and unmarshalled from JSON when scanned.
+func JSON(ptr interface{}) *JSONValue {
+	return &JSONValue{ptr}
+}
+
+// Scan implements sql.Scanner. The target is reset to its zero value before
+// unmarshalling, so a NULL column leaves pointer, map and slice fields nil.
+func (v *JSONValue) Scan(src interface{}) error {
+	reflect.ValueOf(v.ptr).Elem().SetZero()
+
+	switch src := src.(type) {
+	case nil:
+		return nil
+
+	case []byte:
+		return json.Unmarshal(src, v.ptr)
+
+	case string:
+		return json.Unmarshal([]byte(src), v.ptr)
+	}
+
+	return fmt.Errorf("crud2: cannot unmarshal %T as JSON", src)
+}
+
+// Value implements driver.Valuer. Values that marshal to `null`, such as nil
+// pointers and maps, are stored as NULL.
+func (v *JSONValue) Value() (driver.Value, error) {
+	bs, er := json.Marshal(v.ptr)
+	if er != nil {
+		return nil, er
+	}
+
+	if string(bs) == "null" {
+		return nil, nil
+	}
+
+	return string(bs), nil
+}
@@ -14,9 +14,10 @@ type FieldBinder interface {
 // Cloner is an optional optimization to avoid multiple calls to BindFields.
 // When extracting multiple rows, objects extracted after the first can be
 // copied over and the bindings from the first can be re-used. This exchanges
-// N string compares with N word copies.
+// N string compares with N word copies. crudgen emits Clone for every tagged
+// struct, deep-copying pointer, slice and map members.
 type Cloner interface {
-	// Copy should return a new instance of this FieldBinder with all members
+	// Clone should return a new instance of this FieldBinder with all members
 	// set to the current values. The old primitive members can be left as-is,
 	// since they will be overwritten by the next call to BindFields. Non-primitive
 	// members need to be fully copied.
@@ -31,6 +32,14 @@ type FieldEnumerator interface {
 	EnumerateFields() ([]string, []interface{})
 }
 
+// FieldFlagger allows structs to report which of their columns carry a given
+// flag in their struct tags, e.g. `crud:"created_at,dbdefault"`. crudgen
+// implements it for every struct with flagged columns.
+type FieldFlagger interface {
+	// FlaggedFields returns the SQL column names tagged with flag.
+	FlaggedFields(flag string) []string
+}
+
 // Deflater allows structs to optionally provide functionality that is invoked
 // before they are marshalled into the database.
 type Deflater interface {
@@ -0,0 +1,219 @@
+package crud
+
+import (
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OpScanRow is the operation MeteredDialect reports for each row scanned.
+// Unlike the other operations, it is counted per row rather than per
+// statement, and under the row's Go type name rather than its table, as a
+// Dialect's Scan sees neither the query nor the table.
+const OpScanRow = "scanrow"
+
+// Outcomes reported to Metrics.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Metrics receives a measurement for every operation run through a
+// MeteredDialect.
+type Metrics interface {
+	// ObserveOperation records one operation on table. op is OpInsert,
+	// OpUpdate, OpDelete or OpScanRow, and outcome is OutcomeOK or
+	// OutcomeError.
+	ObserveOperation(table, op, outcome string, latency time.Duration)
+}
+
+// MeteredDialect wraps a Dialect to record every Insert, Update and Scan, and
+// every row removed by Delete, in Metrics. Inserts, updates and deletes are
+// recorded per statement under their table, e.g. "foo.insert". Scans are
+// recorded as OpScanRow, once per row read by ScanAll, SelectWhere, Iterate
+// or Page, under the type name of the first FieldBinder, e.g. "Foo.scanrow".
+//
+//	crud.DefaultDialect = crud.MeteredDialect{
+//		Dialect: crud.PostgresDialect{},
+//		Metrics: crud.NewExpvarMetrics("crud"),
+//	}
+type MeteredDialect struct {
+	Dialect Dialect
+	Metrics Metrics
+}
+
+func (d MeteredDialect) record(table, op string, start time.Time, er error) {
+	outcome := OutcomeOK
+	if er != nil {
+		outcome = OutcomeError
+	}
+
+	d.Metrics.ObserveOperation(table, op, outcome, time.Since(start))
+}
+
+func (d MeteredDialect) Scan(rows *sql.Rows, args ...FieldBinder) error {
+	start := time.Now()
+	er := d.Dialect.Scan(rows, args...)
+
+	table := ""
+	if len(args) > 0 {
+		table = reflect.Indirect(reflect.ValueOf(args[0])).Type().Name()
+	}
+
+	d.record(table, OpScanRow, start, er)
+
+	return er
+}
+
+func (d MeteredDialect) Insert(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) (int64, error) {
+	start := time.Now()
+	id, er := d.Dialect.Insert(db, table, sqlIdFieldName, obj)
+
+	d.record(table, OpInsert, start, er)
+
+	return id, er
+}
+
+func (d MeteredDialect) Update(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	start := time.Now()
+	er := d.Dialect.Update(db, table, sqlIdFieldName, obj)
+
+	d.record(table, OpUpdate, start, er)
+
+	return er
+}
+
+// Delete implements RowDeleter, for the rows Delete removes outright.
+func (d MeteredDialect) Delete(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	start := time.Now()
+
+	var er error
+
+	if deleter, ok := d.Dialect.(RowDeleter); ok {
+		er = deleter.Delete(db, table, sqlIdFieldName, obj)
+	} else {
+		er = hardDelete(db, table, sqlIdFieldName, obj)
+	}
+
+	d.record(table, OpDelete, start, er)
+
+	return er
+}
+
+// IsRetryable forwards to the wrapped Dialect, so that wrapping it doesn't
+// disable retries in WithTx.
+func (d MeteredDialect) IsRetryable(er error) bool {
+	return isRetryable(d.Dialect, er)
+}
+
+// SupportsRowValues forwards to the wrapped Dialect, for Page.
+func (d MeteredDialect) SupportsRowValues() bool {
+	comparer, ok := d.Dialect.(RowValueComparer)
+	return ok && comparer.SupportsRowValues()
+}
+
+// LatencyBuckets are the upper bounds of the latency histograms kept by
+// ExpvarMetrics. They are copied by NewExpvarMetrics, so changes only apply
+// to ExpvarMetrics created afterwards.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// ExpvarMetrics implements Metrics by publishing a counter and latency
+// histogram for each (table, operation, outcome) through expvar. They appear
+// in /debug/vars as a map keyed by "table.op.outcome", e.g.
+//
+//	"crud": {"foo.insert.ok": {"count": 2, "sum_seconds": 0.003,
+//		"buckets": {"0.001": 1, "0.005": 2, ..., "+Inf": 2}}}
+//
+// Bucket counts are cumulative.
+type ExpvarMetrics struct {
+	mu      sync.Mutex
+	vars    *expvar.Map
+	buckets []time.Duration
+}
+
+// NewExpvarMetrics publishes an ExpvarMetrics under name. Like
+// expvar.Publish, it panics if name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	return &ExpvarMetrics{
+		vars:    expvar.NewMap(name),
+		buckets: append([]time.Duration(nil), LatencyBuckets...),
+	}
+}
+
+func (m *ExpvarMetrics) ObserveOperation(table, op, outcome string, latency time.Duration) {
+	key := table + "." + op + "." + outcome
+
+	m.mu.Lock()
+	v := m.vars.Get(key)
+
+	if v == nil {
+		v = newLatencySeries(m.buckets)
+		m.vars.Set(key, v)
+	}
+	m.mu.Unlock()
+
+	v.(*latencySeries).observe(latency)
+}
+
+// latencySeries is an expvar.Var holding a count and latency histogram.
+type latencySeries struct {
+	mu      sync.Mutex
+	count   int64
+	sum     time.Duration
+	bounds  []time.Duration
+	buckets []int64
+}
+
+func newLatencySeries(bounds []time.Duration) *latencySeries {
+	return &latencySeries{bounds: bounds, buckets: make([]int64, len(bounds))}
+}
+
+func (s *latencySeries) observe(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	s.sum += latency
+
+	for i, bound := range s.bounds {
+		if latency <= bound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (s *latencySeries) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets := make(map[string]int64, len(s.buckets)+1)
+
+	for i, count := range s.buckets {
+		buckets[strconv.FormatFloat(s.bounds[i].Seconds(), 'g', -1, 64)] = count
+	}
+
+	buckets["+Inf"] = s.count
+
+	bs, _ := json.Marshal(map[string]interface{}{
+		"count":       s.count,
+		"sum_seconds": s.sum.Seconds(),
+		"buckets":     buckets,
+	})
+
+	return string(bs)
+}
@@ -0,0 +1,174 @@
+package crud
+
+import (
+	"database/sql"
+)
+
+// nullGroup is implemented by every NullStruct, regardless of its type
+// parameter, so that nested groups can refer to their parents.
+type nullGroup interface {
+	root() nullGroup
+	adopt(child nullGroup)
+	claimFirst() bool
+	reset()
+	present()
+}
+
+// NullStruct backs a pointer member tagged `,recurse` while a row is being
+// scanned. Its columns are scanned into Value, and the member is only pointed
+// at Value once at least one of those columns turns out to be non-NULL. This
+// allows a struct selected through a LEFT JOIN to come back as nil when the
+// join didn't match.
+//
+// NullStructs are created by crudgen-generated BindFields implementations and
+// are not meant to be used directly.
+type NullStruct[T any] struct {
+	Value T
+
+	ptr      **T
+	parent   nullGroup
+	children []nullGroup
+	bound    bool
+}
+
+// NewNullStruct returns a NullStruct that will set *ptr. parent should be the
+// NullStruct of the enclosing pointer member for nested `,recurse` members,
+// and nil otherwise.
+func NewNullStruct[T any](ptr **T, parent nullGroup) *NullStruct[T] {
+	group := &NullStruct[T]{
+		ptr:    ptr,
+		parent: parent,
+	}
+
+	if parent != nil {
+		parent.adopt(group)
+	}
+
+	return group
+}
+
+func (group *NullStruct[T]) adopt(child nullGroup) {
+	group.children = append(group.children, child)
+}
+
+func (group *NullStruct[T]) root() nullGroup {
+	if group.parent != nil {
+		return group.parent.root()
+	}
+
+	return group
+}
+
+// claimFirst returns true exactly once, for the first field bound anywhere
+// in the group's tree.
+func (group *NullStruct[T]) claimFirst() bool {
+	if group.bound {
+		return false
+	}
+
+	group.bound = true
+	return true
+}
+
+// reset discards the previous row's values. It is invoked when the first
+// bound column of the row is scanned, which allows the bindings to be reused
+// across rows.
+func (group *NullStruct[T]) reset() {
+	var zero T
+
+	group.Value = zero
+	*group.ptr = nil
+
+	for _, child := range group.children {
+		child.reset()
+	}
+}
+
+func (group *NullStruct[T]) present() {
+	*group.ptr = &group.Value
+
+	if group.parent != nil {
+		group.parent.present()
+	}
+}
+
+type nullField[F any] struct {
+	group nullGroup
+	dst   *F
+	first bool
+}
+
+// NullField returns an sql.Scanner that scans a column into dst, which must
+// point into group.Value, and marks the group as present if the column isn't
+// NULL.
+func NullField[T, F any](group *NullStruct[T], dst *F) sql.Scanner {
+	return &nullField[F]{
+		group: group,
+		dst:   dst,
+		first: group.root().claimFirst(),
+	}
+}
+
+func (field *nullField[F]) Scan(src interface{}) error {
+	if field.first {
+		field.group.root().reset()
+	}
+
+	if src == nil {
+		return nil
+	}
+
+	if scanner, ok := convertDest(field.dst).(sql.Scanner); ok {
+		if er := scanner.Scan(src); er != nil {
+			return er
+		}
+
+	} else {
+		var tmp sql.Null[F]
+
+		if er := tmp.Scan(src); er != nil {
+			return er
+		}
+
+		*field.dst = tmp.V
+	}
+
+	field.group.present()
+
+	return nil
+}
+
+type nullScanner struct {
+	group   nullGroup
+	scanner sql.Scanner
+	first   bool
+}
+
+// NullScanner is like NullField, but scans non-NULL columns through scanner,
+// which must write into group.Value. It is used for fields that need custom
+// decoding, such as those tagged with `,json`.
+func NullScanner[T any](group *NullStruct[T], scanner sql.Scanner) sql.Scanner {
+	return &nullScanner{
+		group:   group,
+		scanner: scanner,
+		first:   group.root().claimFirst(),
+	}
+}
+
+func (field *nullScanner) Scan(src interface{}) error {
+	if field.first {
+		field.group.root().reset()
+	}
+
+	if src == nil {
+		return nil
+	}
+
+	if er := field.scanner.Scan(src); er != nil {
+		return er
+	}
+
+	field.group.present()
+
+	return nil
+}
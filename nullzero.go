@@ -0,0 +1,68 @@
+package crud
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+// NullZeroValue stores the zero value of a field as NULL, and scans NULL back
+// into the zero value. It is bound by crudgen for fields tagged with
+// `,nullzero`, which allows nullable columns to use plain Go types where the
+// zero value means "absent".
+type NullZeroValue[T any] struct {
+	ptr *T
+}
+
+// NullZero wraps ptr, a pointer to a field.
+func NullZero[T any](ptr *T) *NullZeroValue[T] {
+	return &NullZeroValue[T]{ptr}
+}
+
+func (v *NullZeroValue[T]) Scan(src interface{}) error {
+	if src == nil {
+		var zero T
+		*v.ptr = zero
+		return nil
+	}
+
+	if scanner, ok := convertDest(v.ptr).(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+
+	var tmp sql.Null[T]
+
+	if er := tmp.Scan(src); er != nil {
+		return er
+	}
+
+	*v.ptr = tmp.V
+	return nil
+}
+
+func (v *NullZeroValue[T]) Value() (driver.Value, error) {
+	if isZero(*v.ptr) {
+		return nil, nil
+	}
+
+	value, er := convertArg(v.ptr)
+	if er != nil {
+		return nil, er
+	}
+
+	return driver.DefaultParameterConverter.ConvertValue(value)
+}
+
+// isZero reports whether value is the zero value of its type. Types with an
+// IsZero method, such as time.Time, decide for themselves.
+func isZero(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+
+	case interface{ IsZero() bool }:
+		return value.IsZero()
+	}
+
+	return reflect.ValueOf(value).IsZero()
+}
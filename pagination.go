@@ -0,0 +1,265 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor marks a position in a keyset-paginated result, as returned by Page.
+// It is opaque, URL-safe, and can be handed to clients as-is. The empty
+// Cursor is the start of the result.
+type Cursor string
+
+// ErrBadCursor is returned by Page for cursors that can't be decoded, or
+// that don't match the order columns.
+var ErrBadCursor = errors.New("crud2: malformed pagination cursor")
+
+// RowValueComparer is implemented by Dialects to report whether they support
+// row value comparisons such as `(a, b) > ($1, $2)`. Page expands them into
+// equivalent OR/AND chains for dialects that don't.
+type RowValueComparer interface {
+	SupportsRowValues() bool
+}
+
+// SupportsRowValues implements RowValueComparer; PostgreSQL has always
+// supported row value comparisons.
+func (PostgresDialect) SupportsRowValues() bool {
+	return true
+}
+
+// SupportsRowValues implements RowValueComparer; SQLite supports row value
+// comparisons since 3.15.
+func (SQLite3Dialect) SupportsRowValues() bool {
+	return true
+}
+
+// Page reads the next page of up to limit rows of table into the slice
+// pointed to by slicePtr, using keyset pagination: rows are ordered by
+// orderCols, ascending, and only those after the cursor are read. The
+// columns should form a unique key, e.g. by ending with the primary key:
+//
+//	SELECT * FROM foo WHERE (foo_time, foo_id) > ($1, $2)
+//	ORDER BY foo_time, foo_id LIMIT 50
+//
+// The returned Cursor points after the last row of the page, and is empty if
+// this was the last page. As with SelectWhere, soft-deleted rows are left out
+// unless db is Unscoped. dialect scans the rows and decides how the
+// comparison is written; nil means DefaultDialect.
+func Page(db DbIsh, dialect Dialect, table string, slicePtr interface{}, orderCols []string, after Cursor, limit int) (Cursor, error) {
+	if dialect == nil {
+		dialect = DefaultDialect
+	}
+
+	sliceVal := reflect.ValueOf(slicePtr)
+
+	if sliceVal.Kind() != reflect.Pointer || sliceVal.Elem().Kind() != reflect.Slice {
+		return "", fmt.Errorf("Argument to crud.Page is not a slice")
+	}
+
+	sliceVal = sliceVal.Elem()
+	elemType := sliceVal.Type().Elem()
+
+	if len(orderCols) == 0 || limit <= 0 {
+		return "", fmt.Errorf("crud2: Page needs order columns and a positive limit")
+	}
+
+	args, er := decodeCursor(after)
+	if er != nil {
+		return "", er
+	}
+
+	if args != nil && len(args) != len(orderCols) {
+		return "", ErrBadCursor
+	}
+
+	where := ""
+
+	if args != nil {
+		where = keysetCondition(dialect, orderCols)
+	}
+
+	q := selectWhereQuery(db, table, reflect.New(elemType).Interface(), where)
+	q += fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(orderCols, ", "), limit)
+
+	rows, er := db.Query(q, args...)
+	if er != nil {
+		return "", er
+	}
+	defer rows.Close()
+
+	start := sliceVal.Len()
+
+	for rows.Next() {
+		newVal := reflect.New(elemType)
+
+		binder, ok := newVal.Interface().(FieldBinder)
+		if !ok {
+			return "", fmt.Errorf("crud2: %s does not implement FieldBinder", newVal.Type())
+		}
+
+		if er := dialect.Scan(rows, binder); er != nil {
+			return "", er
+		}
+
+		sliceVal.Set(reflect.Append(sliceVal, newVal.Elem()))
+	}
+
+	if er := rows.Err(); er != nil {
+		return "", er
+	}
+
+	if sliceVal.Len()-start < limit {
+		return "", nil
+	}
+
+	last, ok := sliceVal.Index(sliceVal.Len() - 1).Addr().Interface().(FieldEnumerator)
+	if !ok {
+		return "", fmt.Errorf("crud2: %s does not implement FieldEnumerator", elemType)
+	}
+
+	return encodeCursor(last, orderCols)
+}
+
+// keysetCondition compares orderCols against the placeholders $1...$n.
+func keysetCondition(dialect Dialect, orderCols []string) string {
+	placeholders := make([]string, len(orderCols))
+
+	for i := range orderCols {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	if comparer, ok := dialect.(RowValueComparer); ok && comparer.SupportsRowValues() {
+		return fmt.Sprintf("(%s) > (%s)", strings.Join(orderCols, ", "), strings.Join(placeholders, ", "))
+	}
+
+	// (a, b, c) > (x, y, z) is equivalent to
+	// a > x OR (a = x AND b > y) OR (a = x AND b = y AND c > z).
+	terms := make([]string, len(orderCols))
+
+	for i := range orderCols {
+		conds := []string{}
+
+		for j := 0; j < i; j++ {
+			conds = append(conds, orderCols[j]+" = "+placeholders[j])
+		}
+
+		conds = append(conds, orderCols[i]+" > "+placeholders[i])
+		terms[i] = "(" + strings.Join(conds, " AND ") + ")"
+	}
+
+	return strings.Join(terms, " OR ")
+}
+
+// cursorValue is a driver.Value tagged with its type, so that it survives
+// the round trip through JSON.
+type cursorValue [2]string
+
+func encodeCursor(obj FieldEnumerator, orderCols []string) (Cursor, error) {
+	fields, values := obj.EnumerateFields()
+	encoded := make([]cursorValue, len(orderCols))
+
+	for i, col := range orderCols {
+		idx := -1
+
+		for j, field := range fields {
+			if strings.EqualFold(field, col) {
+				idx = j
+			}
+		}
+
+		if idx < 0 {
+			return "", fmt.Errorf("crud2: order column %s is not a field of %T", col, obj)
+		}
+
+		arg, er := convertArg(values[idx])
+		if er != nil {
+			return "", er
+		}
+
+		value, er := driver.DefaultParameterConverter.ConvertValue(arg)
+		if er != nil {
+			return "", er
+		}
+
+		switch value := value.(type) {
+		case nil:
+			encoded[i] = cursorValue{"n", ""}
+		case int64:
+			encoded[i] = cursorValue{"i", strconv.FormatInt(value, 10)}
+		case float64:
+			encoded[i] = cursorValue{"f", strconv.FormatFloat(value, 'g', -1, 64)}
+		case bool:
+			encoded[i] = cursorValue{"o", strconv.FormatBool(value)}
+		case string:
+			encoded[i] = cursorValue{"s", value}
+		case []byte:
+			encoded[i] = cursorValue{"b", base64.StdEncoding.EncodeToString(value)}
+		case time.Time:
+			encoded[i] = cursorValue{"t", value.Format(time.RFC3339Nano)}
+		default:
+			return "", fmt.Errorf("crud2: cannot encode %T in a cursor", value)
+		}
+	}
+
+	bs, er := json.Marshal(encoded)
+	if er != nil {
+		return "", er
+	}
+
+	return Cursor(base64.RawURLEncoding.EncodeToString(bs)), nil
+}
+
+// decodeCursor returns the values encoded in cursor, or nil for the empty
+// cursor.
+func decodeCursor(cursor Cursor) ([]interface{}, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	bs, er := base64.RawURLEncoding.DecodeString(string(cursor))
+	if er != nil {
+		return nil, ErrBadCursor
+	}
+
+	var encoded []cursorValue
+
+	if er := json.Unmarshal(bs, &encoded); er != nil || len(encoded) == 0 {
+		return nil, ErrBadCursor
+	}
+
+	values := make([]interface{}, len(encoded))
+
+	for i, value := range encoded {
+		switch value[0] {
+		case "n":
+			values[i] = nil
+		case "i":
+			values[i], er = strconv.ParseInt(value[1], 10, 64)
+		case "f":
+			values[i], er = strconv.ParseFloat(value[1], 64)
+		case "o":
+			values[i], er = strconv.ParseBool(value[1])
+		case "s":
+			values[i] = value[1]
+		case "b":
+			values[i], er = base64.StdEncoding.DecodeString(value[1])
+		case "t":
+			values[i], er = time.Parse(time.RFC3339Nano, value[1])
+		default:
+			er = ErrBadCursor
+		}
+
+		if er != nil {
+			return nil, ErrBadCursor
+		}
+	}
+
+	return values, nil
+}
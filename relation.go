@@ -0,0 +1,319 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RelationKind identifies how the rows of a Relation refer to each other.
+type RelationKind int
+
+const (
+	// HasMany relates a struct to every row of another table whose
+	// foreign key holds its primary key, e.g. a customer's orders.
+	HasMany RelationKind = iota + 1
+
+	// BelongsTo relates a struct to the row of another table its foreign
+	// key points to, e.g. an order's customer.
+	BelongsTo
+
+	// ManyToMany relates a struct to the rows of another table listed
+	// alongside its primary key in a join table, e.g. a post's tags.
+	ManyToMany
+)
+
+// Relation describes a field holding the structs related to its parent, as
+// declared by a `,hasmany`, `,belongsto` or `,manytomany` tag. HasMany and
+// BelongsTo relations are loaded the same way: the related rows are those of
+// Table whose Column equals the parent's Key column. ManyToMany relations go
+// through JoinTable instead, whose columns are named after Key and Column,
+// the primary keys of the parent and of Table.
+type Relation struct {
+	Kind RelationKind
+
+	// Table is the table the related structs are stored in.
+	Table string
+
+	// Key is the parent's column: its primary key for HasMany and
+	// ManyToMany, or its foreign key for BelongsTo.
+	Key string
+
+	// Column is the column of Table matched against Key, or its primary
+	// key for ManyToMany.
+	Column string
+
+	// JoinTable is the join table of a ManyToMany relation.
+	JoinTable string
+
+	// New returns a new related struct to scan a row into.
+	New func() FieldBinder
+
+	// Attach stores the related structs loaded for the parent in its field.
+	Attach func(related []FieldBinder)
+}
+
+// Relater is implemented by structs with relationship fields. crudgen
+// implements it for every struct with a `,hasmany`, `,belongsto` or
+// `,manytomany` field.
+type Relater interface {
+	// CrudRelation returns the relation stored in the named Go field,
+	// bound to the receiver, or nil if there is no such relation.
+	CrudRelation(name string) *Relation
+}
+
+// preloadBatchSize caps the number of keys in each IN list Preload sends,
+// keeping it under the bind parameter limits of the supported databases.
+const preloadBatchSize = 500
+
+// Preload loads the relation stored in the field called name for every
+// element of parents, which must be a slice of structs or pointers to structs
+// that implement Relater. Rather than querying once per parent, the related
+// rows for all of them are read with a single IN query (or one per 500
+// distinct keys), then attached to their parents:
+//
+//	customers := []*Customer{}
+//	crud.SelectWhere(db, "customer", &customers, "")
+//	crud.Preload(db, customers, "Orders")
+//
+// Every parent's field is overwritten, so parents without related rows end up
+// with an empty slice or a nil pointer. As with SelectWhere, soft-deleted rows
+// are left out unless db is Unscoped.
+func Preload(db DbIsh, parents interface{}, name string) error {
+	elems, _, er := structPointers(parents)
+	if er != nil {
+		return er
+	}
+
+	relations := make([]*Relation, 0, len(elems))
+	keys := make([]interface{}, 0, len(elems))
+
+	for _, elem := range elems {
+		relater, ok := elem.(Relater)
+		if !ok {
+			return fmt.Errorf("crud2: %T does not implement Relater", elem)
+		}
+
+		rel := relater.CrudRelation(name)
+		if rel == nil {
+			return fmt.Errorf("crud2: %T has no relation %q", elem, name)
+		}
+
+		key, er := relationKey(elem, rel.Key)
+		if er != nil {
+			return er
+		}
+
+		relations = append(relations, rel)
+		keys = append(keys, key)
+	}
+
+	if len(relations) == 0 {
+		return nil
+	}
+
+	related, er := loadRelated(db, relations[0], keys)
+	if er != nil {
+		return er
+	}
+
+	for i, rel := range relations {
+		if keys[i] == nil {
+			rel.Attach(nil)
+		} else {
+			rel.Attach(related[keys[i]])
+		}
+	}
+
+	return nil
+}
+
+// structPointers returns pointers to the elements of slice, which must be a
+// slice (or a pointer to a slice) of structs or pointers to structs, skipping
+// nil elements. The pointer type of the elements is also returned.
+func structPointers(slice interface{}) ([]interface{}, reflect.Type, error) {
+	sliceVal := reflect.ValueOf(slice)
+
+	if sliceVal.Kind() == reflect.Ptr {
+		sliceVal = sliceVal.Elem()
+	}
+
+	if sliceVal.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("crud2: expected a slice of structs, got %T", slice)
+	}
+
+	ptrType := sliceVal.Type().Elem()
+
+	if ptrType.Kind() != reflect.Ptr {
+		ptrType = reflect.PointerTo(ptrType)
+	}
+
+	if ptrType.Elem().Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("crud2: expected a slice of structs, got %T", slice)
+	}
+
+	elems := make([]interface{}, 0, sliceVal.Len())
+
+	for i := 0; i < sliceVal.Len(); i++ {
+		elem := sliceVal.Index(i)
+
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+		} else {
+			elem = elem.Addr()
+		}
+
+		elems = append(elems, elem.Interface())
+	}
+
+	return elems, ptrType, nil
+}
+
+// loadRelated reads the rows related to keys through rel, grouped by the key
+// they are related to. nil keys are ignored.
+func loadRelated(db DbIsh, rel *Relation, keys []interface{}) (map[interface{}][]FieldBinder, error) {
+	distinct := make([]interface{}, 0, len(keys))
+	seen := map[interface{}]bool{}
+
+	for _, key := range keys {
+		if key != nil && !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, key)
+		}
+	}
+
+	sample := rel.New()
+	columns := []string{"*"}
+
+	if enumerator, ok := sample.(FieldEnumerator); ok {
+		if fields, _ := enumerator.EnumerateFields(); len(fields) > 0 {
+			columns = fields
+		}
+	}
+
+	// Related rows are selected by the column holding the parent's key,
+	// which for ManyToMany is in the join table and is selected alongside
+	// the related columns to group them by.
+	from, qualifier, keyColumn := rel.Table, "", rel.Column
+
+	if rel.Kind == ManyToMany {
+		qualifier = rel.Table
+
+		for i, column := range columns {
+			columns[i] = rel.Table + "." + column
+		}
+
+		keyColumn = rel.JoinTable + "." + rel.Key
+		columns = append(columns, keyColumn+" AS "+joinKeyColumn)
+		from = fmt.Sprintf("%s JOIN %s ON %s.%s = %s.%s", rel.Table, rel.JoinTable, rel.JoinTable, rel.Column, rel.Table, rel.Column)
+	}
+
+	related := map[interface{}][]FieldBinder{}
+
+	for len(distinct) > 0 {
+		batch := distinct[:min(len(distinct), preloadBatchSize)]
+		distinct = distinct[len(batch):]
+
+		q := joinSelectQuery(db, strings.Join(columns, ", "), from, qualifier, sample, inCondition(keyColumn, len(batch), 1))
+
+		if er := scanRelated(db, rel, q, batch, related); er != nil {
+			return nil, er
+		}
+	}
+
+	return related, nil
+}
+
+// joinKeyColumn is the alias the parent's key is selected under when loading
+// a ManyToMany relation.
+const joinKeyColumn = "crud_join_key"
+
+// joinKey binds the joinKeyColumn of a row.
+type joinKey struct {
+	value interface{}
+}
+
+func (key *joinKey) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		if name == joinKeyColumn {
+			values[i] = &key.value
+		}
+	}
+}
+
+func scanRelated(db DbIsh, rel *Relation, q string, args []interface{}, related map[interface{}][]FieldBinder) error {
+	rows, er := db.Query(q, args...)
+	if er != nil {
+		return er
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		obj := rel.New()
+
+		var key interface{}
+
+		if rel.Kind == ManyToMany {
+			joined := &joinKey{}
+
+			if er := Scan(rows, obj, joined); er != nil {
+				return er
+			}
+
+			key = joined.value
+
+			if bs, ok := key.([]byte); ok {
+				key = string(bs)
+			}
+		} else {
+			if er := Scan(rows, obj); er != nil {
+				return er
+			}
+
+			if key, er = relationKey(obj, rel.Column); er != nil {
+				return er
+			}
+		}
+
+		related[key] = append(related[key], obj)
+	}
+
+	return rows.Err()
+}
+
+// inCondition returns "column IN ($first, ...)" with n placeholders.
+func inCondition(column string, n, first int) string {
+	placeholders := make([]string, n)
+
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", first+i)
+	}
+
+	return column + " IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// relationKey returns the value of obj's column in a form that can be passed
+// as a query argument and compared as a map key.
+func relationKey(obj interface{}, column string) (interface{}, error) {
+	enumerator, ok := obj.(FieldEnumerator)
+	if !ok {
+		return nil, fmt.Errorf("crud2: %T does not implement FieldEnumerator", obj)
+	}
+
+	value, found, er := columnValue(enumerator, column)
+	if er != nil {
+		return nil, er
+	}
+
+	if !found {
+		return nil, fmt.Errorf("crud2: %T has no column %s", obj, column)
+	}
+
+	if bs, ok := value.([]byte); ok {
+		return string(bs), nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"log/slog"
+)
+
+const redacted = "[REDACTED]"
+
+// SecretValue wraps the value of a column tagged with `,secret`. The dialects
+// wrap such values when building INSERT and UPDATE statements, so that they
+// are passed to the driver as usual but print as "[REDACTED]", including
+// when logged by an Observer.
+type SecretValue struct {
+	v interface{}
+}
+
+// Secret wraps v in a SecretValue.
+func Secret(v interface{}) SecretValue {
+	return SecretValue{v}
+}
+
+// Value implements driver.Valuer, returning the wrapped value.
+func (s SecretValue) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.v)
+}
+
+func (s SecretValue) String() string {
+	return redacted
+}
+
+func (s SecretValue) GoString() string {
+	return redacted
+}
+
+// MarshalJSON keeps the value redacted when logged by a JSON handler.
+func (s SecretValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+// LogValue implements slog.LogValuer.
+func (s SecretValue) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// wrapSecrets wraps the values of the `,secret` columns of obj in place.
+// fields and values are the columns and arguments of a statement.
+func wrapSecrets(obj interface{}, fields []string, values []interface{}) {
+	secrets := flaggedFields(obj, "secret")
+
+	for i, field := range fields {
+		if contains(secrets, field) {
+			values[i] = Secret(values[i])
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// unwrapper is implemented by DbIsh wrappers such as the one returned by
+// Unscoped, so that options can be found however the wrappers are stacked.
+type unwrapper interface {
+	Unwrap() DbIsh
+}
+
+type unscopedDb struct {
+	DbIsh
+}
+
+func (db unscopedDb) Unwrap() DbIsh {
+	return db.DbIsh
+}
+
+// ExecContext, PrepareContext and QueryContext pass ctx on to db, so that
+// WithContext still applies when wrapped around Unscoped.
+func (db unscopedDb) ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	return execContext(ctx, db.DbIsh, q, args...)
+}
+
+func (db unscopedDb) PrepareContext(ctx context.Context, q string) (*sql.Stmt, error) {
+	return prepareContext(ctx, db.DbIsh, q)
+}
+
+func (db unscopedDb) QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	return queryContext(ctx, db.DbIsh, q, args...)
+}
+
+// Unscoped returns a DbIsh that runs queries against db, but disables soft
+// delete handling for anything passed it: SelectWhere and generated fetch
+// helpers return soft-deleted rows, and Delete removes rows outright.
+func Unscoped(db DbIsh) DbIsh {
+	return unscopedDb{db}
+}
+
+// WithDeleted is an alias for Unscoped.
+func WithDeleted(db DbIsh) DbIsh {
+	return Unscoped(db)
+}
+
+func isUnscoped(db DbIsh) bool {
+	for db != nil {
+		if _, ok := db.(unscopedDb); ok {
+			return true
+		}
+
+		wrapper, ok := db.(unwrapper)
+		if !ok {
+			break
+		}
+
+		db = wrapper.Unwrap()
+	}
+
+	return false
+}
+
+// deletedField returns the `,deleted` column of obj and the address of its
+// *time.Time field, or "" if obj has none.
+func deletedField(obj FieldEnumerator) (string, **time.Time) {
+	columns := flaggedFields(obj, "deleted")
+	if len(columns) == 0 {
+		return "", nil
+	}
+
+	fields, values := obj.EnumerateFields()
+
+	for i, field := range fields {
+		if field == columns[0] {
+			ptr, _ := values[i].(**time.Time)
+			return field, ptr
+		}
+	}
+
+	return "", nil
+}
+
+// IsDeleted reports whether obj has been soft-deleted, i.e. whether its
+// `,deleted` field is set.
+func IsDeleted(obj FieldEnumerator) bool {
+	_, ptr := deletedField(obj)
+	return ptr != nil && *ptr != nil
+}
+
+// Hidden reports whether obj should be left out of results read through db:
+// it has been soft-deleted, and db isn't Unscoped. It is used by generated
+// fetch helpers.
+func Hidden(db DbIsh, obj FieldEnumerator) bool {
+	return IsDeleted(obj) && !isUnscoped(db)
+}
+
+// Delete removes obj, identified by its sqlIdFieldName column, from table.
+// If obj has a field tagged `,deleted` (which must be a *time.Time), the row
+// is soft-deleted instead: the field is set to Now() and obj is saved with
+// DefaultDialect.Update, which also runs obj's update hooks. The field is
+// reset if the Update fails. Passing an Unscoped db always removes the row.
+//
+// obj's BeforeDelete and AfterDelete hooks are run in either case.
+func Delete(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	return hookDelete(db, obj, func() error {
+		return del(db, table, sqlIdFieldName, obj)
+	})
+}
+
+func del(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	if column, ptr := deletedField(obj); column != "" && !isUnscoped(db) {
+		if ptr == nil {
+			return fmt.Errorf("crud2: deleted column %s must be a *time.Time", column)
+		}
+
+		old := *ptr
+		now := Now()
+		*ptr = &now
+
+		if er := Update(db, table, sqlIdFieldName, obj); er != nil {
+			*ptr = old
+			return er
+		}
+
+		return nil
+	}
+
+	if deleter, ok := DefaultDialect.(RowDeleter); ok {
+		return deleter.Delete(db, table, sqlIdFieldName, obj)
+	}
+
+	return hardDelete(db, table, sqlIdFieldName, obj)
+}
+
+// RowDeleter is implemented by Dialects that want to see the rows Delete
+// removes outright, such as MeteredDialect. Soft deletes go through Update.
+type RowDeleter interface {
+	Delete(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error
+}
+
+func hardDelete(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	fields, values := obj.EnumerateFields()
+
+	for i, field := range fields {
+		if field != sqlIdFieldName {
+			continue
+		}
+
+		id, er := convertArg(values[i])
+		if er != nil {
+			return er
+		}
+
+		_, er = db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = $1", table, sqlIdFieldName), id)
+		return er
+	}
+
+	return ErrUnsetPKey
+}
+
+// Restore undoes a soft delete by clearing obj's `,deleted` field and saving
+// it with DefaultDialect.Update.
+func Restore(db DbIsh, table, sqlIdFieldName string, obj FieldEnumerator) error {
+	column, ptr := deletedField(obj)
+
+	if column == "" {
+		return fmt.Errorf("crud2: %T has no deleted column to restore", obj)
+	}
+
+	if ptr == nil {
+		return fmt.Errorf("crud2: deleted column %s must be a *time.Time", column)
+	}
+
+	*ptr = nil
+
+	return Update(db, table, sqlIdFieldName, obj)
+}
+
+// SelectWhere runs `SELECT * FROM table WHERE where` and scans the results
+// into slicePtr with ScanAll. where may be empty to select every row. If the
+// slice's elements have a `,deleted` field, soft-deleted rows are excluded
+// unless db is Unscoped.
+func SelectWhere(db DbIsh, table string, slicePtr interface{}, where string, args ...interface{}) error {
+	sliceType := reflect.TypeOf(slicePtr)
+
+	if sliceType.Kind() != reflect.Pointer || sliceType.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("Argument to crud.SelectWhere is not a slice")
+	}
+
+	q := selectWhereQuery(db, table, reflect.New(sliceType.Elem().Elem()).Interface(), where)
+
+	rows, er := db.Query(q, args...)
+	if er != nil {
+		return er
+	}
+
+	return ScanAll(rows, slicePtr)
+}
+
+// selectWhereQuery builds the query run by SelectWhere for objects like obj.
+func selectWhereQuery(db DbIsh, table string, obj interface{}, where string) string {
+	return selectQuery(db, "*", table, obj, where)
+}
+
+// selectQuery builds a query for columns of table, constrained by where and,
+// unless db is Unscoped, excluding rows soft-deleted according to obj.
+func selectQuery(db DbIsh, columns, table string, obj interface{}, where string) string {
+	return joinSelectQuery(db, columns, table, "", obj, where)
+}
+
+// joinSelectQuery is like selectQuery, selecting from a join whose obj rows
+// come from the table named qualifier, which prefixes the `,deleted` column.
+func joinSelectQuery(db DbIsh, columns, from, qualifier string, obj interface{}, where string) string {
+	conds := []string{}
+
+	if where != "" {
+		conds = append(conds, "("+where+")")
+	}
+
+	if enumerator, ok := obj.(FieldEnumerator); ok && !isUnscoped(db) {
+		if column, _ := deletedField(enumerator); column != "" {
+			if qualifier != "" {
+				column = qualifier + "." + column
+			}
+
+			conds = append(conds, column+" IS NULL")
+		}
+	}
+
+	q := "SELECT " + columns + " FROM " + from
+
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	return q
+}
@@ -1,8 +1,16 @@
 package crud
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,6 +48,219 @@ type ModifiedFoo struct {
 	Time time.Time `crud:"foo_time"`
 }
 
+type Owner struct {
+	Id   int64  `crud:"owner_id"`
+	Name string `crud:"owner_name"`
+}
+
+type Pet struct {
+	Id    int64  `crud:"pet_id"`
+	Name  string `crud:"pet_name"`
+	Owner *Owner `crud:",recurse"`
+}
+
+type JSONPoint struct {
+	X, Y int
+}
+
+type JSONFoo struct {
+	Id       int64             `crud:"jfoo_id"`
+	Settings map[string]string `crud:"jfoo_settings,json"`
+	Tags     []string          `crud:"jfoo_tags,json"`
+	Point    *JSONPoint        `crud:"jfoo_point,json"`
+}
+
+type ArrayFoo struct {
+	Id      int64     `crud:"afoo_id"`
+	Strings []string  `crud:"afoo_strings,array"`
+	Ints    []*int64  `crud:"afoo_ints,array"`
+	Floats  []float64 `crud:"afoo_floats,array"`
+}
+
+type Status int
+
+const (
+	StatusActive Status = iota + 1
+	StatusRetired
+)
+
+func (s Status) MarshalText() ([]byte, error) {
+	switch s {
+	case StatusActive:
+		return []byte("active"), nil
+	case StatusRetired:
+		return []byte("retired"), nil
+	}
+
+	return nil, fmt.Errorf("invalid status %d", s)
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "active":
+		*s = StatusActive
+	case "retired":
+		*s = StatusRetired
+	default:
+		return fmt.Errorf("unknown status %q", text)
+	}
+
+	return nil
+}
+
+type TextFoo struct {
+	Id     int64   `crud:"xfoo_id"`
+	Status Status  `crud:"xfoo_status,text"`
+	Prev   *Status `crud:"xfoo_prev,text"`
+}
+
+// Money is stored through a converter registered in init, as a string of
+// the form "12.34".
+type Money struct {
+	Cents int64
+}
+
+func init() {
+	RegisterConverter(func(m Money) (driver.Value, error) {
+		return fmt.Sprintf("%d.%02d", m.Cents/100, m.Cents%100), nil
+	}, func(src interface{}) (m Money, er error) {
+		var units, cents int64
+
+		if _, er = fmt.Sscanf(fmt.Sprintf("%s", src), "%d.%d", &units, &cents); er != nil {
+			return m, fmt.Errorf("bad money %q: %w", src, er)
+		}
+
+		return Money{units*100 + cents}, nil
+	})
+}
+
+type ConvFoo struct {
+	Id       int64  `crud:"cfoo_id"`
+	Price    Money  `crud:"cfoo_price"`
+	Discount *Money `crud:"cfoo_discount"`
+}
+
+type NullZeroFoo struct {
+	Id    int64     `crud:"zfoo_id"`
+	Name  string    `crud:"zfoo_name,nullzero"`
+	Count int32     `crud:"zfoo_count,nullzero"`
+	When  time.Time `crud:"zfoo_when,nullzero"`
+	Price Money     `crud:"zfoo_price,nullzero"`
+}
+
+type DefaultFoo struct {
+	Id    int64  `crud:"dfoo_id"`
+	Name  string `crud:"dfoo_name"`
+	Token string `crud:"dfoo_token,dbdefault"`
+	Seq   int64  `crud:"dfoo_seq,dbdefault"`
+}
+
+// DefaultOnlyFoo has no column an Update could set.
+type DefaultOnlyFoo struct {
+	Id    int64  `crud:"dfoo_id"`
+	Token string `crud:"dfoo_token,dbdefault"`
+}
+
+type StrKeyFoo struct {
+	Id   string `crud:"sfoo_id"`
+	Name string `crud:"sfoo_name"`
+}
+
+type StampFoo struct {
+	Id      int64      `crud:"stfoo_id"`
+	Name    string     `crud:"stfoo_name"`
+	Created time.Time  `crud:"stfoo_created,created"`
+	Updated *time.Time `crud:"stfoo_updated,updated"`
+}
+
+type SoftFoo struct {
+	Id      int64      `crud:"sofoo_id"`
+	Name    string     `crud:"sofoo_name"`
+	Deleted *time.Time `crud:"sofoo_deleted,deleted"`
+}
+
+type HookFoo struct {
+	Id   int64  `crud:"hfoo_id"`
+	Name string `crud:"hfoo_name"`
+
+	calls []string
+}
+
+type SecretFoo struct {
+	Id       int64  `crud:"secfoo_id"`
+	User     string `crud:"secfoo_user"`
+	Password string `crud:"secfoo_password,secret"`
+}
+
+type SmallKeyFoo struct {
+	Id   int32  `crud:"kfoo_id"`
+	Name string `crud:"kfoo_name"`
+}
+
+type Customer struct {
+	Id     int64    `crud:"customer_id,pk"`
+	Name   string   `crud:"customer_name"`
+	Orders []*Order `crud:",hasmany=orders.order_customer_id"`
+}
+
+type Order struct {
+	Id         int64      `crud:"order_id,pk"`
+	CustomerId *int64     `crud:"order_customer_id"`
+	Total      int64      `crud:"order_total"`
+	Deleted    *time.Time `crud:"order_deleted_at,deleted"`
+	Customer   *Customer  `crud:"order_customer_id,belongsto=customer.customer_id"`
+}
+
+type Post struct {
+	Id    int64  `crud:"post_id,pk"`
+	Title string `crud:"post_title"`
+	Tags  []*Tag `crud:",manytomany=post_tag.tag"`
+}
+
+type Tag struct {
+	Id      int64      `crud:"tag_id,pk"`
+	Name    string     `crud:"tag_name"`
+	Deleted *time.Time `crud:"deleted_at,deleted"`
+}
+
+type hookKey struct{}
+
+func (foo *HookFoo) record(ctx context.Context, db DbIsh, call string) error {
+	foo.calls = append(foo.calls, call)
+
+	if foo.Name == "fail "+call {
+		return fmt.Errorf("%s failed", call)
+	}
+
+	msg := fmt.Sprintf("%s %d %v", call, foo.Id, ctx.Value(hookKey{}))
+	_, er := db.Exec("INSERT INTO audit (audit_msg) VALUES ($1)", msg)
+	return er
+}
+
+func (foo *HookFoo) BeforeInsert(ctx context.Context, db DbIsh) error {
+	return foo.record(ctx, db, "BeforeInsert")
+}
+
+func (foo *HookFoo) AfterInsert(ctx context.Context, db DbIsh) error {
+	return foo.record(ctx, db, "AfterInsert")
+}
+
+func (foo *HookFoo) BeforeUpdate(ctx context.Context, db DbIsh) error {
+	return foo.record(ctx, db, "BeforeUpdate")
+}
+
+func (foo *HookFoo) AfterUpdate(ctx context.Context, db DbIsh) error {
+	return foo.record(ctx, db, "AfterUpdate")
+}
+
+func (foo *HookFoo) BeforeDelete(ctx context.Context, db DbIsh) error {
+	return foo.record(ctx, db, "BeforeDelete")
+}
+
+func (foo *HookFoo) AfterDelete(ctx context.Context, db DbIsh) error {
+	return foo.record(ctx, db, "AfterDelete")
+}
+
 func (foo *ModifiedFoo) CrudDeflate() error {
 	foo.Num += 10
 	return nil
@@ -111,6 +332,133 @@ func createDb() (*sql.DB, error) {
 		return nil, er
 	}
 
+	_, er = db.Exec(`
+		CREATE TABLE owner
+			( owner_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, owner_name VARCHAR(255) NOT NULL
+			);
+
+		CREATE TABLE pet
+			( pet_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, pet_name VARCHAR(255) NOT NULL
+			, pet_owner_id INTEGER REFERENCES owner (owner_id)
+			);
+
+		CREATE TABLE afoo
+			( afoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, afoo_strings TEXT
+			, afoo_ints TEXT
+			, afoo_floats TEXT
+			);
+
+		CREATE TABLE xfoo
+			( xfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, xfoo_status TEXT NOT NULL
+			, xfoo_prev TEXT
+			);
+
+		CREATE TABLE cfoo
+			( cfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, cfoo_price TEXT NOT NULL
+			, cfoo_discount TEXT
+			);
+
+		CREATE TABLE zfoo
+			( zfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, zfoo_name TEXT
+			, zfoo_count INTEGER
+			, zfoo_when TIMESTAMP
+			, zfoo_price TEXT
+			);
+
+		CREATE TABLE dfoo
+			( dfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, dfoo_name TEXT NOT NULL
+			, dfoo_token TEXT NOT NULL DEFAULT (lower(hex(randomblob(8))))
+			, dfoo_seq INTEGER NOT NULL DEFAULT 7
+			);
+
+		CREATE TABLE jfoo
+			( jfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, jfoo_settings TEXT
+			, jfoo_tags TEXT
+			, jfoo_point TEXT
+			);
+
+		CREATE TABLE sfoo
+			( sfoo_id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8))))
+			, sfoo_name TEXT NOT NULL
+			);
+
+		CREATE TABLE stfoo
+			( stfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, stfoo_name TEXT NOT NULL
+			, stfoo_created TIMESTAMP NOT NULL
+			, stfoo_updated TIMESTAMP
+			);
+
+		CREATE TABLE sofoo
+			( sofoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, sofoo_name TEXT NOT NULL
+			, sofoo_deleted TIMESTAMP
+			);
+
+		CREATE TABLE hfoo
+			( hfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, hfoo_name TEXT NOT NULL
+			);
+
+		CREATE TABLE audit
+			( audit_msg TEXT NOT NULL
+			);
+
+		CREATE TABLE secfoo
+			( secfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, secfoo_user TEXT NOT NULL
+			, secfoo_password TEXT NOT NULL
+			);
+
+		CREATE TABLE kfoo
+			( kfoo_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, kfoo_name TEXT NOT NULL
+			);
+
+		CREATE TABLE customer
+			( customer_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, customer_name TEXT NOT NULL
+			);
+
+		CREATE TABLE orders
+			( order_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, order_customer_id INTEGER REFERENCES customer (customer_id)
+			, order_total INTEGER NOT NULL
+			, order_deleted_at TIMESTAMP
+			);
+
+		CREATE TABLE post
+			( post_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, post_title TEXT NOT NULL
+			);
+
+		CREATE TABLE tag
+			( tag_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, tag_name TEXT NOT NULL
+			, deleted_at TIMESTAMP
+			);
+
+		CREATE TABLE post_tag
+			( post_id INTEGER NOT NULL REFERENCES post (post_id)
+			, tag_id INTEGER NOT NULL REFERENCES tag (tag_id)
+			, deleted_at TIMESTAMP
+			, PRIMARY KEY (post_id, tag_id)
+			);
+	`)
+
+	if er != nil {
+		db.Close()
+		return nil, er
+	}
+
 	return db, nil
 }
 
@@ -679,3 +1027,1941 @@ func TestInflateDeflate(t *testing.T) {
 		t.Errorf("Second round trip failed: got %d", fout.Num)
 	}
 }
+
+func TestNullRecurse(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	_, er = db.Exec(`
+		INSERT INTO owner (owner_id, owner_name) VALUES (1, 'alice');
+		INSERT INTO pet (pet_id, pet_name, pet_owner_id) VALUES (1, 'rex', 1);
+		INSERT INTO pet (pet_id, pet_name, pet_owner_id) VALUES (2, 'stray', NULL);
+		INSERT INTO pet (pet_id, pet_name, pet_owner_id) VALUES (3, 'tom', 1);
+	`)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er := db.Query(`
+		SELECT pet_id, pet_name, owner_id, owner_name
+		FROM pet LEFT JOIN owner ON pet_owner_id = owner_id
+		ORDER BY pet_id
+	`)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	pets := []Pet{}
+
+	if er := ScanAll(rows, &pets); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(pets) != 3 {
+		t.Fatalf("Got wrong number of pets: %d (expected %d)", len(pets), 3)
+	}
+
+	for _, i := range []int{0, 2} {
+		if pets[i].Owner == nil {
+			t.Errorf("Owner of %s - nil", pets[i].Name)
+
+		} else if pets[i].Owner.Name != "alice" {
+			t.Errorf("Owner of %s - mismatch: %q", pets[i].Name, pets[i].Owner.Name)
+		}
+	}
+
+	if pets[1].Owner != nil {
+		t.Errorf("Owner of %s - not nil: %#v", pets[1].Name, pets[1].Owner)
+	}
+
+	if pets[0].Owner == pets[2].Owner {
+		t.Errorf("Owners share storage")
+	}
+
+	names, values := pets[1].EnumerateFields()
+
+	for i, name := range names {
+		if (name == "owner_id" || name == "owner_name") && values[i] != nil {
+			t.Errorf("%s - expected NULL, got %#v", name, values[i])
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	var Int64 int64 = 64
+	var String string = "string"
+
+	f1 := &OptionalFoo{
+		Int64:  &Int64,
+		String: &String,
+	}
+
+	f2, ok := f1.Clone().(*OptionalFoo)
+	if !ok {
+		t.Fatalf("Clone returned the wrong type")
+	}
+
+	if f2.Int64 == f1.Int64 || f2.String == f1.String {
+		t.Fatalf("Clone did not copy pointer members")
+	}
+
+	if *f2.Int64 != Int64 || *f2.String != String {
+		t.Errorf("Clone mismatch: %d, %q", *f2.Int64, *f2.String)
+	}
+
+	if f2.Int8 != nil {
+		t.Errorf("Clone allocated a nil member")
+	}
+
+	p1 := &Pet{Name: "rex", Owner: &Owner{Name: "alice"}}
+	p2 := p1.Clone().(*Pet)
+
+	p2.Owner.Name = "bob"
+
+	if p1.Owner.Name != "alice" {
+		t.Errorf("Clone shares ,recurse pointer members")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f1 := &JSONFoo{
+		Settings: map[string]string{"theme": "dark"},
+		Tags:     []string{"a", "b"},
+		Point:    &JSONPoint{X: 1, Y: 2},
+	}
+
+	if f1.Id, er = Insert(db, "jfoo", "jfoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := Insert(db, "jfoo", "jfoo_id", &JSONFoo{}); er != nil {
+		t.Fatal(er)
+	}
+
+	var raw string
+
+	if er := db.QueryRow("SELECT jfoo_settings FROM jfoo WHERE jfoo_id = $1", f1.Id).Scan(&raw); er != nil {
+		t.Fatal(er)
+	}
+
+	if raw != `{"theme":"dark"}` {
+		t.Errorf("Settings stored as %s", raw)
+	}
+
+	var nulls int
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM jfoo WHERE jfoo_settings IS NULL AND jfoo_point IS NULL").Scan(&nulls); er != nil {
+		t.Fatal(er)
+	}
+
+	if nulls != 1 {
+		t.Errorf("Expected nil members to be stored as NULL, got %d rows", nulls)
+	}
+
+	rows, er := db.Query("SELECT * FROM jfoo ORDER BY jfoo_id")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []JSONFoo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 2 {
+		t.Fatalf("Got wrong number of foos: %d (expected %d)", len(foos), 2)
+	}
+
+	if foos[0].Settings["theme"] != "dark" {
+		t.Errorf("Settings mismatch: %#v", foos[0].Settings)
+	}
+
+	if len(foos[0].Tags) != 2 || foos[0].Tags[1] != "b" {
+		t.Errorf("Tags mismatch: %#v", foos[0].Tags)
+	}
+
+	if foos[0].Point == nil || *foos[0].Point != *f1.Point {
+		t.Errorf("Point mismatch: %#v", foos[0].Point)
+	}
+
+	if foos[1].Settings != nil || foos[1].Tags != nil || foos[1].Point != nil {
+		t.Errorf("Expected NULL columns to scan as nil: %#v", foos[1])
+	}
+}
+
+func TestArray(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	var seven int64 = 7
+
+	f1 := &ArrayFoo{
+		Strings: []string{"a", `"quoted", \escaped`, ""},
+		Ints:    []*int64{&seven, nil},
+		Floats:  []float64{},
+	}
+
+	if f1.Id, er = Insert(db, "afoo", "afoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := Insert(db, "afoo", "afoo_id", &ArrayFoo{}); er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er := db.Query("SELECT * FROM afoo ORDER BY afoo_id")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []ArrayFoo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 2 {
+		t.Fatalf("Got wrong number of foos: %d (expected %d)", len(foos), 2)
+	}
+
+	if len(foos[0].Strings) != 3 || foos[0].Strings[1] != f1.Strings[1] {
+		t.Errorf("Strings mismatch: %#v", foos[0].Strings)
+	}
+
+	if len(foos[0].Ints) != 2 || foos[0].Ints[0] == nil || *foos[0].Ints[0] != 7 || foos[0].Ints[1] != nil {
+		t.Errorf("Ints mismatch: %#v", foos[0].Ints)
+	}
+
+	if foos[0].Floats == nil || len(foos[0].Floats) != 0 {
+		t.Errorf("Floats mismatch: %#v", foos[0].Floats)
+	}
+
+	if foos[1].Strings != nil || foos[1].Ints != nil || foos[1].Floats != nil {
+		t.Errorf("Expected NULL columns to scan as nil: %#v", foos[1])
+	}
+
+	// Integers have to survive the JSON encoding exactly, including those
+	// too large for a float64.
+	million, huge := int64(1000000), int64(1<<60+1)
+
+	f2 := &ArrayFoo{Ints: []*int64{&million, &huge}, Floats: []float64{1e6, 0.1}}
+
+	if _, er := Insert(db, "afoo", "afoo_id", f2); er != nil {
+		t.Fatal(er)
+	}
+
+	got := &ArrayFoo{}
+
+	if er := Get(db, "afoo", "afoo_id", f2.Id, got); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(got.Ints) != 2 || got.Ints[0] == nil || *got.Ints[0] != million || got.Ints[1] == nil || *got.Ints[1] != huge {
+		t.Errorf("Ints didn't round-trip: %v", got.Ints)
+	}
+
+	if len(got.Floats) != 2 || got.Floats[0] != 1e6 || got.Floats[1] != 0.1 {
+		t.Errorf("Floats didn't round-trip: %v", got.Floats)
+	}
+}
+
+func TestText(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	prev := StatusActive
+
+	f1 := &TextFoo{
+		Status: StatusRetired,
+		Prev:   &prev,
+	}
+
+	if f1.Id, er = Insert(db, "xfoo", "xfoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	var raw string
+
+	if er := db.QueryRow("SELECT xfoo_status FROM xfoo").Scan(&raw); er != nil {
+		t.Fatal(er)
+	}
+
+	if raw != "retired" {
+		t.Errorf("Status stored as %q", raw)
+	}
+
+	if _, er := Insert(db, "xfoo", "xfoo_id", &TextFoo{}); er == nil {
+		t.Errorf("Expected Insert to fail to marshal an invalid status")
+	}
+
+	rows, er := db.Query("SELECT * FROM xfoo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []TextFoo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 || foos[0].Status != StatusRetired || foos[0].Prev == nil || *foos[0].Prev != StatusActive {
+		t.Fatalf("Round trip mismatch: %#v", foos)
+	}
+
+	if _, er := db.Exec("INSERT INTO xfoo (xfoo_status) VALUES ('bogus')"); er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er = db.Query("SELECT * FROM xfoo WHERE xfoo_prev IS NULL")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos = []TextFoo{}
+
+	if er := ScanAll(rows, &foos); er == nil {
+		t.Errorf("Expected Scan to fail on an unknown status")
+
+	} else if !strings.Contains(er.Error(), `"xfoo_status"`) {
+		t.Errorf("Expected error to name the column: %s", er)
+	}
+}
+
+func TestConverter(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f1 := &ConvFoo{
+		Price:    Money{1234},
+		Discount: &Money{5},
+	}
+
+	if f1.Id, er = Insert(db, "cfoo", "cfoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := Insert(db, "cfoo", "cfoo_id", &ConvFoo{Price: Money{100}}); er != nil {
+		t.Fatal(er)
+	}
+
+	var raw string
+
+	if er := db.QueryRow("SELECT cfoo_price FROM cfoo WHERE cfoo_id = $1", f1.Id).Scan(&raw); er != nil {
+		t.Fatal(er)
+	}
+
+	if raw != "12.34" {
+		t.Errorf("Price stored as %q", raw)
+	}
+
+	f1.Price.Cents = 999
+
+	if er := Update(db, "cfoo", "cfoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er := db.Query("SELECT * FROM cfoo ORDER BY cfoo_id")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []ConvFoo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 2 {
+		t.Fatalf("Got wrong number of foos: %d (expected %d)", len(foos), 2)
+	}
+
+	if foos[0].Price.Cents != 999 || foos[0].Discount == nil || foos[0].Discount.Cents != 5 {
+		t.Errorf("Round trip mismatch: %#v", foos[0])
+	}
+
+	if foos[1].Price.Cents != 100 || foos[1].Discount != nil {
+		t.Errorf("Round trip mismatch: %#v", foos[1])
+	}
+}
+
+func TestNullZero(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f1 := &NullZeroFoo{
+		Name:  "name",
+		Count: 3,
+		When:  time.Unix(1338, 0).UTC(),
+		Price: Money{250},
+	}
+
+	if f1.Id, er = Insert(db, "zfoo", "zfoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	f2 := &NullZeroFoo{}
+
+	if f2.Id, er = Insert(db, "zfoo", "zfoo_id", f2); er != nil {
+		t.Fatal(er)
+	}
+
+	var nulls int
+
+	if er := db.QueryRow(`
+		SELECT COUNT(*) FROM zfoo
+		WHERE zfoo_name IS NULL AND zfoo_count IS NULL AND zfoo_when IS NULL AND zfoo_price IS NULL
+	`).Scan(&nulls); er != nil {
+		t.Fatal(er)
+	}
+
+	if nulls != 1 {
+		t.Errorf("Expected zero values to be stored as NULL, got %d rows", nulls)
+	}
+
+	rows, er := db.Query("SELECT * FROM zfoo ORDER BY zfoo_id")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []NullZeroFoo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 2 {
+		t.Fatalf("Got wrong number of foos: %d (expected %d)", len(foos), 2)
+	}
+
+	if foos[0].Name != f1.Name || foos[0].Count != f1.Count || !foos[0].When.Equal(f1.When) || foos[0].Price != f1.Price {
+		t.Errorf("Round trip mismatch: %#v", foos[0])
+	}
+
+	if foos[1] != *f2 {
+		t.Errorf("Expected NULL columns to scan as zero values: %#v", foos[1])
+	}
+}
+
+func TestDbDefault(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := &DefaultFoo{
+		Name:  "first",
+		Token: "ignored",
+		Seq:   -1,
+	}
+
+	if f.Id, er = Insert(db, "dfoo", "dfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Id == 0 {
+		t.Errorf("Expected Insert to return non-0 id")
+	}
+
+	if len(f.Token) != 16 || f.Seq != 7 {
+		t.Errorf("Defaults not read back: %#v", f)
+	}
+
+	token := f.Token
+
+	if _, er := db.Exec("UPDATE dfoo SET dfoo_seq = 8"); er != nil {
+		t.Fatal(er)
+	}
+
+	f.Name = "second"
+	f.Token = "ignored"
+
+	if er := Update(db, "dfoo", "dfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Token != token || f.Seq != 8 {
+		t.Errorf("Defaults not read back after Update: %#v", f)
+	}
+
+	var name string
+
+	if er := db.QueryRow("SELECT dfoo_name FROM dfoo WHERE dfoo_id = $1", f.Id).Scan(&name); er != nil {
+		t.Fatal(er)
+	}
+
+	if name != "second" {
+		t.Errorf("Update did not apply: %q", name)
+	}
+
+	if er := Update(db, "dfoo", "dfoo_id", &DefaultOnlyFoo{Id: f.Id}); er != ErrNoColumns {
+		t.Errorf("Expected Update without columns to set to fail with ErrNoColumns, got %v", er)
+	}
+}
+
+func TestInsertSetsPrimaryKey(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := &Foo{Num: 1, Str: "one", Time: time.Now()}
+
+	id, er := Insert(db, "foo", "foo_id", f)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if id == 0 || f.Id != id {
+		t.Errorf("Expected Insert to set Id to %d (got %d)", id, f.Id)
+	}
+
+	k := &SmallKeyFoo{Name: "small"}
+
+	if id, er = Insert(db, "kfoo", "kfoo_id", k); er != nil {
+		t.Fatal(er)
+	}
+
+	if k.Id == 0 || int64(k.Id) != id {
+		t.Errorf("Expected Insert to set int32 Id to %d (got %d)", id, k.Id)
+	}
+
+	s := &StrKeyFoo{Name: "str"}
+
+	if id, er = Insert(db, "sfoo", "sfoo_id", s); er != nil {
+		t.Fatal(er)
+	}
+
+	if id != 0 {
+		t.Errorf("Expected Insert to return 0 for a string key (got %d)", id)
+	}
+
+	if len(s.Id) != 16 {
+		t.Fatalf("Expected Insert to set string Id (got %q)", s.Id)
+	}
+
+	var name string
+
+	if er := db.QueryRow("SELECT sfoo_name FROM sfoo WHERE sfoo_id = $1", s.Id).Scan(&name); er != nil {
+		t.Fatal(er)
+	}
+
+	if name != "str" {
+		t.Errorf("Read back wrong row: %q", name)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	clock := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	Now = func() time.Time { return clock }
+	defer func() { Now = time.Now }()
+
+	f := &StampFoo{Name: "first"}
+
+	if _, er := Insert(db, "stfoo", "stfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if !f.Created.Equal(clock) || f.Updated == nil || !f.Updated.Equal(clock) {
+		t.Errorf("Insert did not set timestamps: %v %v", f.Created, f.Updated)
+	}
+
+	created := clock
+	clock = clock.Add(time.Hour)
+
+	f.Name = "second"
+	f.Created = time.Time{}
+
+	if er := Update(db, "stfoo", "stfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if !f.Updated.Equal(clock) {
+		t.Errorf("Update did not refresh updated timestamp: %v", f.Updated)
+	}
+
+	rows, er := db.Query("SELECT * FROM stfoo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []StampFoo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(foos))
+	}
+
+	if !foos[0].Created.Equal(created) {
+		t.Errorf("Update overwrote created timestamp: %v", foos[0].Created)
+	}
+
+	if foos[0].Updated == nil || !foos[0].Updated.Equal(clock) {
+		t.Errorf("Updated timestamp not stored: %v", foos[0].Updated)
+	}
+
+	saved := clock
+	clock = clock.Add(time.Hour)
+
+	if er := Update(db, "nosuchtable", "stfoo_id", f); er == nil {
+		t.Fatal("Expected Update of a missing table to fail")
+	}
+
+	if !f.Updated.Equal(saved) {
+		t.Errorf("Failed Update left updated timestamp changed: %v", f.Updated)
+	}
+
+	g := &StampFoo{Name: "unsaved"}
+
+	if _, er := Insert(db, "nosuchtable", "stfoo_id", g); er == nil {
+		t.Fatal("Expected Insert into a missing table to fail")
+	}
+
+	if !g.Created.IsZero() || g.Updated != nil {
+		t.Errorf("Failed Insert left timestamps set: %v %v", g.Created, g.Updated)
+	}
+
+	soft := &SoftFoo{Name: "soft"}
+
+	if _, er := Insert(db, "sofoo", "sofoo_id", soft); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Delete(db, "nosuchtable", "sofoo_id", soft); er == nil {
+		t.Fatal("Expected Delete from a missing table to fail")
+	}
+
+	if soft.Deleted != nil {
+		t.Errorf("Failed Delete left deleted timestamp set: %v", soft.Deleted)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f1 := &SoftFoo{Name: "kept"}
+	f2 := &SoftFoo{Name: "deleted"}
+
+	for _, f := range []*SoftFoo{f1, f2} {
+		if _, er := Insert(db, "sofoo", "sofoo_id", f); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if er := Delete(db, "sofoo", "sofoo_id", f2); er != nil {
+		t.Fatal(er)
+	}
+
+	if !IsDeleted(f2) || IsDeleted(f1) {
+		t.Errorf("Expected only f2 to be deleted")
+	}
+
+	foos := []SoftFoo{}
+
+	if er := SelectWhere(db, "sofoo", &foos, ""); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 || foos[0].Name != "kept" {
+		t.Errorf("Expected SelectWhere to skip deleted rows: %#v", foos)
+	}
+
+	foos = nil
+
+	if er := SelectWhere(Unscoped(db), "sofoo", &foos, "sofoo_name = $1", "deleted"); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 || !IsDeleted(&foos[0]) {
+		t.Errorf("Expected Unscoped SelectWhere to return the deleted row: %#v", foos)
+	}
+
+	if er := Restore(db, "sofoo", "sofoo_id", f2); er != nil {
+		t.Fatal(er)
+	}
+
+	foos = nil
+
+	if er := SelectWhere(db, "sofoo", &foos, ""); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 2 {
+		t.Errorf("Expected Restore to bring the row back: %#v", foos)
+	}
+
+	if er := Delete(Unscoped(db), "sofoo", "sofoo_id", f1); er != nil {
+		t.Fatal(er)
+	}
+
+	var count int
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM sofoo").Scan(&count); er != nil {
+		t.Fatal(er)
+	}
+
+	if count != 1 {
+		t.Errorf("Expected Unscoped Delete to remove the row (%d left)", count)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	tx, er := db.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer tx.Rollback()
+
+	ctxDb := WithContext(context.WithValue(context.Background(), hookKey{}, "ctx"), tx)
+
+	f := &HookFoo{Name: "first"}
+
+	if _, er := Insert(ctxDb, "hfoo", "hfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Update(ctxDb, "hfoo", "hfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Delete(ctxDb, "hfoo", "hfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	expected := "BeforeInsert AfterInsert BeforeUpdate AfterUpdate BeforeDelete AfterDelete"
+
+	if calls := strings.Join(f.calls, " "); calls != expected {
+		t.Errorf("Expected hooks %q, got %q", expected, calls)
+	}
+
+	rows, er := tx.Query("SELECT audit_msg FROM audit")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	msgs := []string{}
+
+	for rows.Next() {
+		var msg string
+
+		if er := rows.Scan(&msg); er != nil {
+			t.Fatal(er)
+		}
+
+		msgs = append(msgs, msg)
+	}
+	rows.Close()
+
+	if len(msgs) != 6 || msgs[0] != "BeforeInsert 0 ctx" || msgs[1] != fmt.Sprintf("AfterInsert %d ctx", f.Id) {
+		t.Errorf("Unexpected audit rows: %q", msgs)
+	}
+
+	for _, call := range []string{"BeforeInsert", "BeforeUpdate", "BeforeDelete"} {
+		f := &HookFoo{Id: f.Id, Name: "fail " + call}
+
+		switch call {
+		case "BeforeInsert":
+			_, er = Insert(tx, "hfoo", "hfoo_id", f)
+		case "BeforeUpdate":
+			er = Update(tx, "hfoo", "hfoo_id", f)
+		case "BeforeDelete":
+			er = Delete(tx, "hfoo", "hfoo_id", f)
+		}
+
+		if er == nil || len(f.calls) != 1 {
+			t.Errorf("Expected %s error to abort the write (calls: %q)", call, f.calls)
+		}
+	}
+
+	var count int
+
+	if er := tx.QueryRow("SELECT COUNT(*) FROM hfoo WHERE hfoo_name LIKE 'fail%'").Scan(&count); er != nil {
+		t.Fatal(er)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected aborted writes to leave no rows, found %d", count)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	opts := &TxOptions{Backoff: time.Millisecond}
+
+	count := func() (n int) {
+		if er := db.QueryRow("SELECT COUNT(*) FROM hfoo").Scan(&n); er != nil {
+			t.Fatal(er)
+		}
+		return
+	}
+
+	insert := func(tx DbIsh) error {
+		_, er := Insert(tx, "hfoo", "hfoo_id", &HookFoo{Name: "tx"})
+		return er
+	}
+
+	if er := WithTx(ctx, db, opts, insert); er != nil {
+		t.Fatal(er)
+	}
+
+	if n := count(); n != 1 {
+		t.Errorf("Expected WithTx to commit, found %d rows", n)
+	}
+
+	failure := fmt.Errorf("failure")
+
+	er = WithTx(ctx, db, opts, func(tx DbIsh) error {
+		if er := insert(tx); er != nil {
+			return er
+		}
+		return failure
+	})
+
+	if er != failure {
+		t.Errorf("Expected WithTx to return fn's error, got %v", er)
+	}
+
+	if n := count(); n != 1 {
+		t.Errorf("Expected WithTx to roll back, found %d rows", n)
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("Expected panic to propagate, got %v", p)
+			}
+		}()
+
+		WithTx(ctx, db, opts, func(tx DbIsh) error {
+			insert(tx)
+			panic("boom")
+		})
+	}()
+
+	if n := count(); n != 1 {
+		t.Errorf("Expected WithTx to roll back on panic, found %d rows", n)
+	}
+
+	attempts := 0
+
+	er = WithTx(ctx, db, opts, func(tx DbIsh) error {
+		if attempts++; attempts < 3 {
+			insert(tx)
+			return fmt.Errorf("database is locked")
+		}
+		return insert(tx)
+	})
+
+	if er != nil || attempts != 3 {
+		t.Errorf("Expected WithTx to retry until success (attempts: %d, er: %v)", attempts, er)
+	}
+
+	if n := count(); n != 2 {
+		t.Errorf("Expected only the successful attempt to commit, found %d rows", n)
+	}
+
+	attempts = 0
+
+	er = WithTx(ctx, db, &TxOptions{MaxAttempts: 2, Backoff: time.Millisecond}, func(tx DbIsh) error {
+		attempts++
+		return fmt.Errorf("database table is locked")
+	})
+
+	if er == nil || attempts != 2 {
+		t.Errorf("Expected WithTx to give up after 2 attempts (attempts: %d, er: %v)", attempts, er)
+	}
+}
+
+func TestNestedTx(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	names := func() string {
+		foos := []HookFoo{}
+
+		if er := SelectWhere(db, "hfoo", &foos, ""); er != nil {
+			t.Fatal(er)
+		}
+
+		out := []string{}
+
+		for _, foo := range foos {
+			out = append(out, foo.Name)
+		}
+
+		return strings.Join(out, " ")
+	}
+
+	insert := func(db DbIsh, name string) {
+		if _, er := Insert(db, "hfoo", "hfoo_id", &HookFoo{Name: name}); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	outer, er := Begin(db)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	insert(outer, "outer")
+
+	inner, er := outer.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	insert(inner, "inner")
+
+	if er := inner.Rollback(); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := inner.Exec("SELECT 1"); er != sql.ErrTxDone {
+		t.Errorf("Expected rolled back Tx to be done, got %v", er)
+	}
+
+	kept, er := Begin(outer)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	insert(kept, "kept")
+
+	if er := kept.Commit(); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := outer.Commit(); er != nil {
+		t.Fatal(er)
+	}
+
+	if n := names(); n != "outer kept" {
+		t.Errorf("Expected only the inner savepoint to roll back, got %q", n)
+	}
+
+	failure := fmt.Errorf("failure")
+
+	er = WithTx(context.Background(), db, nil, func(tx DbIsh) error {
+		insert(tx, "withtx")
+
+		er := WithTx(context.Background(), tx, nil, func(tx DbIsh) error {
+			insert(tx, "nested")
+			return failure
+		})
+
+		if er != failure {
+			t.Errorf("Expected nested WithTx to return its error, got %v", er)
+		}
+
+		return nil
+	})
+
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	sqlTx, er := db.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	borrowed, er := Begin(sqlTx)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	insert(borrowed, "borrowed")
+
+	if er := borrowed.Rollback(); er != nil {
+		t.Fatal(er)
+	}
+
+	insert(sqlTx, "sqltx")
+
+	if er := sqlTx.Commit(); er != nil {
+		t.Fatal(er)
+	}
+
+	if n := names(); n != "outer kept withtx sqltx" {
+		t.Errorf("Unexpected rows after nested transactions: %q", n)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	events := []QueryEvent{}
+	logs := &strings.Builder{}
+
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	observer := SlogObserver(logger)
+
+	idb := Instrument(db, ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		events = append(events, event)
+		observer.ObserveQuery(ctx, event)
+	}))
+
+	f := &SecretFoo{User: "bob", Password: "hunter2"}
+
+	if _, er := Insert(idb, "secfoo", "secfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	f.Password = "hunter3"
+
+	if er := Update(idb, "secfoo", "secfoo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []SecretFoo{}
+
+	if er := SelectWhere(idb, "secfoo", &foos, "secfoo_user = $1", "bob"); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 || foos[0].Password != "hunter3" {
+		t.Fatalf("Secret column not stored: %#v", foos)
+	}
+
+	if _, er := idb.Exec("DELETE FROM nosuchtable"); er == nil {
+		t.Fatal("Expected DELETE from a missing table to fail")
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+
+	expected := []struct {
+		op, table string
+		args      int
+		rows      int64
+		failed    bool
+	}{
+		{OpInsert, "secfoo", 2, 1, false},
+		{OpUpdate, "secfoo", 3, 1, false},
+		{OpScan, "secfoo", 1, -1, false},
+		{OpDelete, "nosuchtable", 0, -1, true},
+	}
+
+	for i, e := range expected {
+		event := events[i]
+
+		if event.Op != e.op || event.Table != e.table || len(event.Args) != e.args || event.RowsAffected != e.rows || (event.Err != nil) != e.failed {
+			t.Errorf("Event %d mismatch: %+v", i, event)
+		}
+	}
+
+	secrets := 0
+
+	for _, arg := range events[0].Args {
+		if _, ok := arg.(SecretValue); ok {
+			secrets++
+		}
+	}
+
+	if secrets != 1 {
+		t.Errorf("Expected the secret argument to be wrapped: %#v", events[0].Args)
+	}
+
+	out := logs.String()
+
+	if strings.Contains(out, "hunter") || !strings.Contains(out, redacted) {
+		t.Errorf("Expected secret to be redacted from logs:\n%s", out)
+	}
+
+	if !strings.Contains(out, `"args":["[REDACTED]","bob"]`) {
+		t.Errorf("Expected arguments to be logged by value:\n%s", out)
+	}
+}
+
+func TestWithTxKeepsWrappers(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	events := []QueryEvent{}
+
+	idb := Instrument(db, ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		events = append(events, event)
+	}))
+
+	soft := &SoftFoo{Name: "soft"}
+
+	if _, er := Insert(db, "sofoo", "sofoo_id", soft); er != nil {
+		t.Fatal(er)
+	}
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "tx")
+
+	er = WithTx(ctx, Unscoped(WithContext(context.Background(), idb)), nil, func(tx DbIsh) error {
+		if contextOf(tx).Value(ctxKey{}) != "tx" {
+			t.Errorf("Expected the transaction's context inside WithTx")
+		}
+
+		if !isUnscoped(tx) {
+			t.Errorf("Expected the transaction to be unscoped")
+		}
+
+		return Delete(tx, "sofoo", "sofoo_id", soft)
+	})
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if len(events) != 1 || events[0].Op != OpDelete || events[0].Table != "sofoo" {
+		t.Fatalf("Expected the delete inside WithTx to be observed: %+v", events)
+	}
+
+	n := 0
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM sofoo").Scan(&n); er != nil || n != 0 {
+		t.Errorf("Expected Unscoped Delete inside WithTx to remove the row, found %d (%v)", n, er)
+	}
+}
+
+func TestWithContextOverUnscoped(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	foos := []SoftFoo{}
+
+	if er := SelectWhere(WithContext(ctx, Unscoped(db)), "sofoo", &foos, ""); !errors.Is(er, context.Canceled) {
+		t.Errorf("Expected a canceled context to stop an Unscoped query, got %v", er)
+	}
+
+	if _, er := Insert(WithContext(ctx, Unscoped(db)), "sofoo", "sofoo_id", &SoftFoo{Name: "soft"}); !errors.Is(er, context.Canceled) {
+		t.Errorf("Expected a canceled context to stop an Unscoped insert, got %v", er)
+	}
+}
+
+func TestWithContextOverInstrument(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	type ctxKey struct{}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "outer"))
+	cancel()
+
+	events := []QueryEvent{}
+	contexts := []interface{}{}
+
+	idb := Instrument(db, ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		events = append(events, event)
+		contexts = append(contexts, ctx.Value(ctxKey{}))
+	}))
+
+	foos := []SoftFoo{}
+
+	if er := SelectWhere(WithContext(ctx, idb), "sofoo", &foos, ""); !errors.Is(er, context.Canceled) {
+		t.Errorf("Expected a canceled context to stop an instrumented query, got %v", er)
+	}
+
+	if _, er := Insert(WithContext(ctx, idb), "sofoo", "sofoo_id", &SoftFoo{Name: "soft"}); !errors.Is(er, context.Canceled) {
+		t.Errorf("Expected a canceled context to stop an instrumented insert, got %v", er)
+	}
+
+	if len(events) != 2 || !errors.Is(events[0].Err, context.Canceled) || !errors.Is(events[1].Err, context.Canceled) {
+		t.Errorf("Expected the canceled statements to be observed: %+v", events)
+	}
+
+	if fmt.Sprint(contexts) != "[outer outer]" {
+		t.Errorf("Expected the observer to be passed the statements' context, got %v", contexts)
+	}
+}
+
+type recordingMetrics []string
+
+func (m *recordingMetrics) ObserveOperation(table, op, outcome string, latency time.Duration) {
+	*m = append(*m, table+"."+op+"."+outcome)
+}
+
+func TestMeteredDialect(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	recorded := &recordingMetrics{}
+	expvars := NewExpvarMetrics("crud_test_metrics")
+
+	for _, metrics := range []Metrics{recorded, expvars} {
+		d := MeteredDialect{SQLite3Dialect{}, metrics}
+
+		f := newFoo()
+
+		if _, er := d.Insert(db, "foo", "foo_id", f); er != nil {
+			t.Fatal(er)
+		}
+
+		if er := d.Update(db, "foo", "foo_id", f); er != nil {
+			t.Fatal(er)
+		}
+
+		if er := d.Update(db, "nosuchtable", "foo_id", f); er == nil {
+			t.Fatal("Expected Update of a missing table to fail")
+		}
+
+		if _, er := d.Insert(db, "nosuchtable", "foo_id", f); er == nil {
+			t.Fatal("Expected Insert into a missing table to fail")
+		}
+
+		rows, er := db.Query("SELECT * FROM foo")
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		for rows.Next() {
+			if er := d.Scan(rows, &Foo{}); er != nil {
+				t.Fatal(er)
+			}
+		}
+		rows.Close()
+	}
+
+	expected := "foo.insert.ok foo.update.ok nosuchtable.update.error nosuchtable.insert.error Foo.scanrow.ok"
+
+	if ops := strings.Join(*recorded, " "); ops != expected {
+		t.Errorf("Expected operations %q, got %q", expected, ops)
+	}
+
+	vars := expvar.Get("crud_test_metrics").String()
+
+	for _, key := range []string{`"foo.insert.ok": {"buckets"`, `"foo.update.ok": {"buckets"`, `"nosuchtable.update.error"`, `"nosuchtable.insert.error"`, `"Foo.scanrow.ok"`} {
+		if !strings.Contains(vars, key) {
+			t.Errorf("Expected expvar output to contain %s:\n%s", key, vars)
+		}
+	}
+
+	if !strings.Contains(vars, `"count":2`) {
+		t.Errorf("Expected two scans to be counted:\n%s", vars)
+	}
+
+	defer func(buckets []time.Duration) {
+		LatencyBuckets = buckets
+	}(LatencyBuckets)
+
+	LatencyBuckets = append(LatencyBuckets, time.Minute)
+
+	expvars.ObserveOperation("foo", OpInsert, OutcomeOK, time.Second)
+
+	if vars := expvar.Get("crud_test_metrics").String(); strings.Contains(vars, `"60"`) {
+		t.Errorf("Expected changes to LatencyBuckets not to affect existing metrics:\n%s", vars)
+	}
+}
+
+func TestMeteredDefaultDialect(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	recorded := &recordingMetrics{}
+
+	defer func(dialect Dialect) {
+		DefaultDialect = dialect
+	}(DefaultDialect)
+
+	DefaultDialect = MeteredDialect{DefaultDialect, recorded}
+
+	f := newFoo()
+
+	if _, er := Insert(db, "foo", "foo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := Insert(db, "foo", "foo_id", newFoo()); er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []Foo{}
+
+	if er := SelectWhere(db, "foo", &foos, ""); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Delete(db, "foo", "foo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	soft := &SoftFoo{Name: "soft"}
+
+	if _, er := Insert(db, "sofoo", "sofoo_id", soft); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Delete(db, "sofoo", "sofoo_id", soft); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Delete(db, "nosuchtable", "foo_id", f); er == nil {
+		t.Fatal("Expected Delete from a missing table to fail")
+	}
+
+	expected := "foo.insert.ok foo.insert.ok Foo.scanrow.ok Foo.scanrow.ok foo.delete.ok sofoo.insert.ok sofoo.update.ok nosuchtable.delete.error"
+
+	if ops := strings.Join(*recorded, " "); ops != expected {
+		t.Errorf("Expected operations %q, got %q", expected, ops)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	for i := int64(1); i <= 3; i++ {
+		f := newFoo()
+		f.Num = i
+
+		if _, er := Insert(db, "foo", "foo_id", f); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	foos, er := Select[Foo](ctx, db, "SELECT * FROM foo WHERE foo_num >= $1 ORDER BY foo_num", 2)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 2 || foos[0].Num != 2 || foos[1].Num != 3 {
+		t.Errorf("Unexpected Select result: %#v", foos)
+	}
+
+	if all, er := All[Foo](ctx, db, "foo"); er != nil || len(all) != 3 {
+		t.Errorf("Expected All to return 3 rows (got %d, %v)", len(all), er)
+	}
+
+	first, er := First[Foo](ctx, db, "SELECT * FROM foo ORDER BY foo_num DESC")
+	if er != nil || first.Num != 3 {
+		t.Errorf("Unexpected First result: %#v, %v", first, er)
+	}
+
+	if _, er := First[Foo](ctx, db, "SELECT * FROM foo WHERE foo_num > 3"); er != ErrNotFound || !errors.Is(er, sql.ErrNoRows) {
+		t.Errorf("Expected ErrNotFound wrapping sql.ErrNoRows, got %v", er)
+	}
+
+	one, er := One[Foo](ctx, db, "SELECT * FROM foo WHERE foo_num = $1", 1)
+	if er != nil || one.Num != 1 {
+		t.Errorf("Unexpected One result: %#v, %v", one, er)
+	}
+
+	if _, er := One[Foo](ctx, db, "SELECT * FROM foo"); er != ErrTooManyRows {
+		t.Errorf("Expected ErrTooManyRows, got %v", er)
+	}
+
+	if _, er := One[Foo](ctx, db, "SELECT * FROM foo WHERE foo_num = 0"); er != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", er)
+	}
+
+	soft := []*SoftFoo{{Name: "live"}, {Name: "gone"}}
+
+	for _, f := range soft {
+		if _, er := Insert(db, "sofoo", "sofoo_id", f); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if er := Delete(db, "sofoo", "sofoo_id", soft[1]); er != nil {
+		t.Fatal(er)
+	}
+
+	if live, er := Select[SoftFoo](ctx, db, "SELECT * FROM sofoo"); er != nil || len(live) != 1 || live[0].Name != "live" {
+		t.Errorf("Expected Select to skip soft-deleted rows: %#v, %v", live, er)
+	}
+
+	if every, er := All[SoftFoo](ctx, Unscoped(db), "sofoo"); er != nil || len(every) != 2 {
+		t.Errorf("Expected Unscoped All to include soft-deleted rows: %#v, %v", every, er)
+	}
+
+	if _, er := One[SoftFoo](ctx, db, "SELECT * FROM sofoo"); er != nil {
+		t.Errorf("Expected One to ignore the soft-deleted row, got %v", er)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	for i := int64(1); i <= 5; i++ {
+		f := newFoo()
+		f.Num = i
+
+		if _, er := Insert(db, "foo", "foo_id", f); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	rows, er := db.Query("SELECT * FROM foo ORDER BY foo_num")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	seen := []*Foo{}
+
+	for foo, er := range Iterate[Foo](rows) {
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		seen = append(seen, foo)
+	}
+
+	if len(seen) != 5 || seen[0].Num != 1 || seen[4].Num != 5 || seen[0] == seen[1] {
+		t.Errorf("Expected 5 distinct rows in order: %#v", seen)
+	}
+
+	rows, er = db.Query("SELECT * FROM foo ORDER BY foo_num")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	count := 0
+
+	for range Iterate[Foo](rows) {
+		if count++; count == 2 {
+			break
+		}
+	}
+
+	// With a single connection, this only succeeds if the early break
+	// closed rows.
+	if _, er := Insert(db, "foo", "foo_id", newFoo()); er != nil {
+		t.Fatalf("Expected rows to be closed after break: %v", er)
+	}
+
+	rows, er = db.Query("SELECT foo_id, foo_str AS foo_num FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	for foo, er := range Iterate[Foo](rows) {
+		if er == nil || foo != nil {
+			t.Errorf("Expected a scan error, got %#v", foo)
+		}
+	}
+}
+
+// plainDialect hides the optional interfaces of the Dialect it wraps.
+type plainDialect struct {
+	Dialect
+}
+
+func TestPage(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	for _, num := range []int64{3, 1, 2, 1, 3, 2, 1} {
+		f := newFoo()
+		f.Num = num
+
+		if _, er := Insert(db, "foo", "foo_id", f); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if plain := keysetCondition(plainDialect{SQLite3Dialect{}}, []string{"a", "b"}); plain != "(a > $1) OR (a = $1 AND b > $2)" {
+		t.Errorf("Unexpected expanded keyset condition: %s", plain)
+	}
+
+	for _, dialect := range []Dialect{SQLite3Dialect{}, plainDialect{SQLite3Dialect{}}} {
+		var cursor Cursor
+		pages := []string{}
+
+		for {
+			foos := []Foo{}
+
+			next, er := Page(db, dialect, "foo", &foos, []string{"foo_num", "foo_id"}, cursor, 3)
+			if er != nil {
+				t.Fatal(er)
+			}
+
+			page := []string{}
+
+			for _, foo := range foos {
+				page = append(page, fmt.Sprintf("%d/%d", foo.Num, foo.Id))
+			}
+
+			pages = append(pages, strings.Join(page, " "))
+
+			if next == "" {
+				break
+			}
+
+			if len(pages) > 5 {
+				t.Fatal("Page never returned an empty cursor")
+			}
+
+			cursor = next
+		}
+
+		expected := "1/2 1/4 1/7, 2/3 2/6 3/1, 3/5"
+
+		if got := strings.Join(pages, ", "); got != expected {
+			t.Errorf("%T: expected pages %q, got %q", dialect, expected, got)
+		}
+	}
+
+	foos := []Foo{}
+
+	if _, er := Page(db, nil, "foo", &foos, []string{"foo_num", "foo_id"}, "not a cursor", 3); er != ErrBadCursor {
+		t.Errorf("Expected ErrBadCursor, got %v", er)
+	}
+
+	next, er := Page(db, nil, "foo", &foos, []string{"foo_time", "foo_str", "foo_id"}, "", 2)
+	if er != nil || next == "" {
+		t.Fatalf("Expected a cursor for the second page (%v)", er)
+	}
+
+	if _, er := Page(db, nil, "foo", &foos, []string{"foo_num", "foo_id"}, next, 2); er != ErrBadCursor {
+		t.Errorf("Expected ErrBadCursor for a cursor with other columns, got %v", er)
+	}
+
+	if _, er := Page(db, nil, "foo", &foos, []string{"foo_time", "foo_str", "foo_id"}, next, 10); er != nil || len(foos) != 7 {
+		t.Errorf("Expected the rest of the rows after a time/string cursor (%d rows, %v)", len(foos), er)
+	}
+}
+
+func TestGet(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	events := []QueryEvent{}
+	idb := Instrument(db, ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		events = append(events, event)
+	}))
+
+	f := newFoo()
+
+	if _, er := Insert(db, "foo", "foo_id", f); er != nil {
+		t.Fatal(er)
+	}
+
+	got := &Foo{}
+
+	if er := Get(idb, "foo", "foo_id", f.Id, got); er != nil {
+		t.Fatal(er)
+	}
+
+	if got.Id != f.Id || got.Num != f.Num || got.Str != f.Str || !got.Time.Equal(f.Time) {
+		t.Errorf("Get returned %#v, expected %#v", got, f)
+	}
+
+	if q := events[0].Query; !strings.HasPrefix(q, "SELECT foo_id, foo_num, foo_str, foo_time FROM foo") {
+		t.Errorf("Expected Get to select the mapped columns, got %q", q)
+	}
+
+	if er := Get(db, "foo", "foo_id", f.Id+1, &Foo{}); er != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", er)
+	}
+
+	if _, er := db.Exec("UPDATE foo SET foo_num = 99 WHERE foo_id = $1", f.Id); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "foo", "foo_id", got); er != nil {
+		t.Fatal(er)
+	}
+
+	if got.Num != 99 || got.Id != f.Id {
+		t.Errorf("Reload did not refresh the object: %#v", got)
+	}
+
+	soft := &SoftFoo{Name: "soft"}
+
+	if _, er := Insert(db, "sofoo", "sofoo_id", soft); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Delete(db, "sofoo", "sofoo_id", soft); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Get(db, "sofoo", "sofoo_id", soft.Id, &SoftFoo{}); er != ErrNotFound {
+		t.Errorf("Expected Get to treat soft-deleted rows as missing, got %v", er)
+	}
+
+	if er := Reload(Unscoped(db), "sofoo", "sofoo_id", soft); er != nil || !IsDeleted(soft) {
+		t.Errorf("Expected Unscoped Reload to find the soft-deleted row (%v)", er)
+	}
+
+	if _, er := db.Exec("DELETE FROM foo"); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "foo", "foo_id", got); er != ErrNotFound {
+		t.Errorf("Expected Reload of a removed row to return ErrNotFound, got %v", er)
+	}
+}
+
+func TestPreload(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	customers := []*Customer{{Name: "alice"}, {Name: "bob"}, {Name: "carol"}}
+
+	for _, customer := range customers {
+		if _, er := Insert(db, "customer", "customer_id", customer); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	orders := []*Order{
+		{CustomerId: &customers[0].Id, Total: 10},
+		{CustomerId: &customers[1].Id, Total: 20},
+		{CustomerId: &customers[0].Id, Total: 30},
+		{CustomerId: &customers[0].Id, Total: 40},
+		{Total: 50},
+	}
+
+	for _, order := range orders {
+		if _, er := Insert(db, "orders", "order_id", order); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if er := Delete(db, "orders", "order_id", orders[3]); er != nil {
+		t.Fatal(er)
+	}
+
+	events := []QueryEvent{}
+	idb := Instrument(db, ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		events = append(events, event)
+	}))
+
+	if er := Preload(idb, customers, "Orders"); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected Preload to run 1 query, ran %d", len(events))
+	}
+
+	if q := events[0].Query; !strings.Contains(q, "order_customer_id IN ($1, $2, $3)") {
+		t.Errorf("Expected Preload to batch the keys, got %q", q)
+	}
+
+	totals := func(orders []*Order) (out []int64) {
+		for _, order := range orders {
+			out = append(out, order.Total)
+		}
+		return
+	}
+
+	if got := totals(customers[0].Orders); fmt.Sprint(got) != "[10 30]" {
+		t.Errorf("Expected alice's live orders to be [10 30], got %v", got)
+	}
+
+	if got := totals(customers[1].Orders); fmt.Sprint(got) != "[20]" {
+		t.Errorf("Expected bob's orders to be [20], got %v", got)
+	}
+
+	if customers[2].Orders == nil || len(customers[2].Orders) != 0 {
+		t.Errorf("Expected carol to have an empty slice of orders, got %#v", customers[2].Orders)
+	}
+
+	if er := Preload(Unscoped(db), customers, "Orders"); er != nil {
+		t.Fatal(er)
+	}
+
+	if got := totals(customers[0].Orders); fmt.Sprint(got) != "[10 30 40]" {
+		t.Errorf("Expected Unscoped to include soft-deleted orders, got %v", got)
+	}
+
+	// Slices of structs work as well as slices of pointers, and parents
+	// sharing a key are loaded once.
+	byValue := []Order{*orders[0], *orders[1], *orders[2], *orders[4]}
+
+	events = events[:0]
+
+	if er := Preload(idb, byValue, "Customer"); er != nil {
+		t.Fatal(er)
+	}
+
+	if q := events[0].Query; !strings.Contains(q, "customer_id IN ($1, $2)") {
+		t.Errorf("Expected Preload to deduplicate keys, got %q", q)
+	}
+
+	for i, want := range []string{"alice", "bob", "alice"} {
+		if byValue[i].Customer == nil || byValue[i].Customer.Name != want {
+			t.Errorf("Expected order %d to belong to %s, got %#v", i, want, byValue[i].Customer)
+		}
+	}
+
+	if byValue[3].Customer != nil {
+		t.Errorf("Expected an order without a customer to have none, got %#v", byValue[3].Customer)
+	}
+
+	if er := Preload(db, customers, "Pets"); er == nil {
+		t.Errorf("Expected Preload of an unknown relation to fail")
+	}
+
+	if er := Preload(db, []*Foo{newFoo()}, "Orders"); er == nil {
+		t.Errorf("Expected Preload of a struct without relations to fail")
+	}
+}
+
+func TestAssociations(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	posts := []*Post{{Title: "first"}, {Title: "second"}, {Title: "third"}}
+	tags := []*Tag{{Name: "go"}, {Name: "sql"}, {Name: "misc"}}
+
+	for _, post := range posts {
+		if _, er := Insert(db, "post", "post_id", post); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	for _, tag := range tags {
+		if _, er := Insert(db, "tag", "tag_id", tag); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	for _, pair := range [][2]int{{0, 0}, {0, 1}, {0, 1}, {1, 1}, {1, 2}} {
+		if er := Associate(db, posts[pair[0]], tags[pair[1]], "post_tag"); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	names := func(tags []*Tag) (out []string) {
+		for _, tag := range tags {
+			out = append(out, tag.Name)
+		}
+		sort.Strings(out)
+		return
+	}
+
+	events := []QueryEvent{}
+	idb := Instrument(db, ObserverFunc(func(ctx context.Context, event QueryEvent) {
+		events = append(events, event)
+	}))
+
+	if er := Preload(idb, posts, "Tags"); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected Preload to run 1 query, ran %d", len(events))
+	}
+
+	if q := events[0].Query; !strings.Contains(q, "JOIN post_tag ON post_tag.tag_id = tag.tag_id") {
+		t.Errorf("Expected Preload to go through the join table, got %q", q)
+	}
+
+	for i, want := range []string{"[go sql]", "[misc sql]", "[]"} {
+		if got := fmt.Sprint(names(posts[i].Tags)); got != want {
+			t.Errorf("Expected post %d to have tags %s, got %s", i, want, got)
+		}
+	}
+
+	if er := Dissociate(db, posts[0], tags[0], "post_tag"); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := ReplaceAssociations(db, posts[1], []Tag{*tags[0], *tags[2]}, "post_tag"); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := ReplaceAssociations(db, posts[2], []*Tag{}, "post_tag"); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Preload(db, posts, "Tags"); er != nil {
+		t.Fatal(er)
+	}
+
+	for i, want := range []string{"[sql]", "[go misc]", "[]"} {
+		if got := fmt.Sprint(names(posts[i].Tags)); got != want {
+			t.Errorf("Expected post %d to have tags %s after the changes, got %s", i, want, got)
+		}
+	}
+
+	if er := Delete(db, "tag", "tag_id", tags[2]); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Preload(db, posts, "Tags"); er != nil {
+		t.Fatal(er)
+	}
+
+	if got := fmt.Sprint(names(posts[1].Tags)); got != "[go]" {
+		t.Errorf("Expected soft-deleted tags to be left out, got %s", got)
+	}
+
+	if er := ReplaceAssociations(db, posts[1], []*Post{posts[0]}, "post_tag"); er == nil {
+		t.Errorf("Expected ReplaceAssociations to reject a join on two post_id columns")
+	}
+
+	if er := Associate(db, posts[0], &Foo{}, "post_tag"); er == nil {
+		t.Errorf("Expected Associate to reject a struct without a ,pk column")
+	}
+}
+
+// countingDialect counts the calls to its Scan.
+type countingDialect struct {
+	Dialect
+	scans *int
+}
+
+func (d countingDialect) Scan(rows *sql.Rows, args ...FieldBinder) error {
+	*d.scans++
+	return d.Dialect.Scan(rows, args...)
+}
+
+func TestScanAllUsesDialect(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, er := Insert(db, "foo", "foo_id", newFoo()); er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	scans := 0
+
+	defer func(dialect Dialect) {
+		DefaultDialect = dialect
+	}(DefaultDialect)
+
+	DefaultDialect = countingDialect{DefaultDialect, &scans}
+
+	rows, er := db.Query("SELECT * FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []Foo{}
+
+	if er := ScanAll(rows, &foos); er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 3 || scans != 3 {
+		t.Errorf("Expected ScanAll to pass all 3 rows to the dialect, got %d foos and %d scans", len(foos), scans)
+	}
+
+	if er := SelectWhere(db, "foo", &foos, ""); er != nil {
+		t.Fatal(er)
+	}
+
+	if scans != 6 {
+		t.Errorf("Expected SelectWhere to pass all 3 rows to the dialect, got %d scans", scans-3)
+	}
+
+	if rows, er = db.Query("SELECT * FROM foo"); er != nil {
+		t.Fatal(er)
+	}
+
+	for _, er := range Iterate[Foo](rows) {
+		if er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if scans != 9 {
+		t.Errorf("Expected Iterate to pass all 3 rows to the dialect, got %d scans", scans-6)
+	}
+}
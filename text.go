@@ -0,0 +1,122 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+)
+
+// TextValue stores a value implementing encoding.TextMarshaler and
+// encoding.TextUnmarshaler, such as an enum, as its text representation. It is
+// bound by crudgen for fields tagged with `,text`.
+type TextValue struct {
+	column string
+	v      interface {
+		encoding.TextMarshaler
+		encoding.TextUnmarshaler
+	}
+}
+
+// Text wraps v, which is bound to column. The column name is used to report
+// values that fail to unmarshal.
+func Text(column string, v interface {
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+}) *TextValue {
+	return &TextValue{column, v}
+}
+
+func (v *TextValue) Scan(src interface{}) error {
+	text, er := textBytes(v.column, src)
+	if er != nil {
+		return er
+	}
+
+	if text == nil {
+		return fmt.Errorf("crud2: column %q is NULL, which cannot be unmarshalled into %T", v.column, v.v)
+	}
+
+	if er := v.v.UnmarshalText(text); er != nil {
+		return fmt.Errorf("crud2: column %q: %w", v.column, er)
+	}
+
+	return nil
+}
+
+func (v *TextValue) Value() (driver.Value, error) {
+	return marshalText(v.column, v.v)
+}
+
+// NullTextValue is like TextValue, but for pointer fields. NULL columns are
+// scanned as nil and nil fields are stored as NULL.
+type NullTextValue[T any, PT interface {
+	*T
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+}] struct {
+	column string
+	ptr    *PT
+}
+
+// NullText wraps ptr, a pointer to a pointer field, which is bound to column.
+func NullText[T any, PT interface {
+	*T
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+}](column string, ptr *PT) *NullTextValue[T, PT] {
+	return &NullTextValue[T, PT]{column, ptr}
+}
+
+func (v *NullTextValue[T, PT]) Scan(src interface{}) error {
+	text, er := textBytes(v.column, src)
+	if er != nil {
+		return er
+	}
+
+	if text == nil {
+		*v.ptr = nil
+		return nil
+	}
+
+	value := PT(new(T))
+
+	if er := value.UnmarshalText(text); er != nil {
+		return fmt.Errorf("crud2: column %q: %w", v.column, er)
+	}
+
+	*v.ptr = value
+	return nil
+}
+
+func (v *NullTextValue[T, PT]) Value() (driver.Value, error) {
+	if *v.ptr == nil {
+		return nil, nil
+	}
+
+	return marshalText(v.column, *v.ptr)
+}
+
+// textBytes returns the text of a scanned column, or nil if it was NULL.
+func textBytes(column string, src interface{}) ([]byte, error) {
+	switch src := src.(type) {
+	case nil:
+		return nil, nil
+
+	case []byte:
+		return append([]byte{}, src...), nil
+
+	case string:
+		return []byte(src), nil
+	}
+
+	return nil, fmt.Errorf("crud2: column %q: cannot unmarshal %T as text", column, src)
+}
+
+func marshalText(column string, v encoding.TextMarshaler) (driver.Value, error) {
+	text, er := v.MarshalText()
+	if er != nil {
+		return nil, fmt.Errorf("crud2: column %q: %w", column, er)
+	}
+
+	return string(text), nil
+}
@@ -0,0 +1,64 @@
+package crud
+
+import (
+	"fmt"
+	"time"
+)
+
+// Now returns the time written to `,created` and `,updated` columns. It can
+// be replaced, e.g. with a fixed clock in tests.
+var Now = time.Now
+
+// touchTimestamps sets the columns of obj tagged with any of flags to the
+// current time. fields and values are as returned by obj.EnumerateFields.
+// restore sets the columns back to their previous values, for when the write
+// they were set for fails.
+func touchTimestamps(obj FieldEnumerator, fields []string, values []interface{}, flags ...string) (restore func(), er error) {
+	var columns []string
+
+	for _, flag := range flags {
+		columns = append(columns, flaggedFields(obj, flag)...)
+	}
+
+	var undo []func()
+
+	restore = func() {
+		for _, fn := range undo {
+			fn()
+		}
+	}
+
+	if len(columns) == 0 {
+		return restore, nil
+	}
+
+	now := Now()
+
+	for i, field := range fields {
+		if !contains(columns, field) {
+			continue
+		}
+
+		switch dst := values[i].(type) {
+		case nil:
+			// The field is inside a nil `,recurse` pointer member.
+
+		case *time.Time:
+			old := *dst
+			undo = append(undo, func() { *dst = old })
+			*dst = now
+
+		case **time.Time:
+			old := *dst
+			undo = append(undo, func() { *dst = old })
+			t := now
+			*dst = &t
+
+		default:
+			restore()
+			return nil, fmt.Errorf("crud2: cannot set timestamp column %s of type %T", field, dst)
+		}
+	}
+
+	return restore, nil
+}
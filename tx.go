@@ -0,0 +1,315 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// RetryClassifier is implemented by Dialects that can tell which errors are
+// caused by contention between transactions, and are likely to succeed if
+// the transaction is retried.
+type RetryClassifier interface {
+	IsRetryable(er error) bool
+}
+
+// TxOptions configures WithTx. The zero value runs transactions with the
+// driver's default isolation level and retries up to 5 attempts.
+type TxOptions struct {
+	sql.TxOptions
+
+	// MaxAttempts is the number of times the transaction is attempted before
+	// the last error is returned. Defaults to 5; 1 disables retries.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, which doubles after every
+	// attempt up to MaxBackoff. They default to 10ms and 1s respectively.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Dialect classifies errors as retryable. Defaults to DefaultDialect;
+	// if it doesn't implement RetryClassifier, nothing is retried.
+	Dialect Dialect
+}
+
+type txBeginner interface {
+	BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Tx is a transaction that implements DbIsh and can be nested. Begin on a Tx
+// starts a savepoint within it, returned as another Tx: committing that Tx
+// releases the savepoint, and rolling it back undoes only the work done since
+// it was started. Only the outermost Tx actually commits.
+//
+// A Tx runs its queries with the context it was begun with.
+type Tx struct {
+	tx  *sql.Tx
+	ctx context.Context
+
+	// savepoint names the savepoint backing a nested Tx, and is "" for the
+	// outermost one.
+	savepoint string
+	done      bool
+}
+
+var savepointSeq atomic.Uint64
+
+// Begin starts a transaction on db. If db is already a transaction (either a
+// Tx or an *sql.Tx), a savepoint is started within it instead, so library
+// code can call Begin whether or not its caller holds a transaction. The
+// context db was wrapped with by WithContext, if any, is used.
+func Begin(db DbIsh) (*Tx, error) {
+	return BeginTx(contextOf(db), db, nil)
+}
+
+// BeginTx is like Begin, with an explicit context and options. opts only
+// apply if a new transaction is started, and may be nil.
+func BeginTx(ctx context.Context, db DbIsh, opts *sql.TxOptions) (*Tx, error) {
+	for inner := db; inner != nil; {
+		switch inner := inner.(type) {
+		case *Tx:
+			if inner.done {
+				return nil, sql.ErrTxDone
+			}
+
+			return beginSavepoint(ctx, inner.tx)
+
+		case *sql.Tx:
+			return beginSavepoint(ctx, inner)
+
+		case txBeginner:
+			tx, er := inner.BeginTx(ctx, opts)
+			if er != nil {
+				return nil, er
+			}
+
+			return &Tx{tx: tx, ctx: ctx}, nil
+		}
+
+		wrapper, ok := inner.(unwrapper)
+		if !ok {
+			break
+		}
+
+		inner = wrapper.Unwrap()
+	}
+
+	return nil, fmt.Errorf("crud2: %T can't begin transactions", db)
+}
+
+func beginSavepoint(ctx context.Context, tx *sql.Tx) (*Tx, error) {
+	name := fmt.Sprintf("crud_sp%d", savepointSeq.Add(1))
+
+	if _, er := tx.ExecContext(ctx, "SAVEPOINT "+name); er != nil {
+		return nil, er
+	}
+
+	return &Tx{tx: tx, ctx: ctx, savepoint: name}, nil
+}
+
+// Begin starts a savepoint within tx.
+func (tx *Tx) Begin() (*Tx, error) {
+	return BeginTx(tx.ctx, tx, nil)
+}
+
+// Commit commits the transaction, or releases the savepoint of a nested Tx.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+
+	tx.done = true
+
+	if tx.savepoint == "" {
+		return tx.tx.Commit()
+	}
+
+	_, er := tx.tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+	return er
+}
+
+// Rollback aborts the transaction, or rolls back to the savepoint of a
+// nested Tx, leaving the enclosing transaction usable.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+
+	tx.done = true
+
+	if tx.savepoint == "" {
+		return tx.tx.Rollback()
+	}
+
+	if _, er := tx.tx.ExecContext(tx.ctx, "ROLLBACK TO SAVEPOINT "+tx.savepoint); er != nil {
+		return er
+	}
+
+	_, er := tx.tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+	return er
+}
+
+// Context returns the context tx runs its queries with.
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+func (tx *Tx) Exec(q string, args ...interface{}) (sql.Result, error) {
+	if tx.done {
+		return nil, sql.ErrTxDone
+	}
+
+	return tx.tx.ExecContext(tx.ctx, q, args...)
+}
+
+func (tx *Tx) Prepare(q string) (*sql.Stmt, error) {
+	if tx.done {
+		return nil, sql.ErrTxDone
+	}
+
+	return tx.tx.PrepareContext(tx.ctx, q)
+}
+
+func (tx *Tx) Query(q string, args ...interface{}) (*sql.Rows, error) {
+	if tx.done {
+		return nil, sql.ErrTxDone
+	}
+
+	return tx.tx.QueryContext(tx.ctx, q, args...)
+}
+
+// WithTx runs fn in a transaction begun on db with BeginTx. The transaction is
+// committed if fn returns nil and rolled back otherwise; if fn panics it is
+// rolled back and the panic propagates. fn is passed the Tx, which carries
+// ctx for hooks to see, wrapped as db was by Instrument, Unscoped and
+// WithContext.
+//
+// If fn or the commit fails with an error the dialect considers retryable,
+// such as a lock timeout or serialization failure, the whole transaction is
+// retried after a backoff. fn should therefore not have side effects outside
+// of the transaction. opts may be nil.
+//
+// If db is already a transaction, fn runs in a savepoint within it and is
+// not retried, as contention has to be resolved by retrying the outermost
+// transaction.
+func WithTx(ctx context.Context, db DbIsh, opts *TxOptions, fn func(tx DbIsh) error) error {
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+
+	if inTx(db) {
+		attempts = 1
+	}
+
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		er := runTx(ctx, db, &opts.TxOptions, fn)
+		if er == nil || attempt >= attempts || !isRetryable(opts.Dialect, er) {
+			return er
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return er
+
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func runTx(ctx context.Context, db DbIsh, opts *sql.TxOptions, fn func(tx DbIsh) error) error {
+	tx, er := BeginTx(ctx, db, opts)
+	if er != nil {
+		return er
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if er := fn(rewrap(db, tx)); er != nil {
+		tx.Rollback()
+		return er
+	}
+
+	return tx.Commit()
+}
+
+// inTx reports whether db, or a DbIsh it wraps, is a transaction.
+func inTx(db DbIsh) bool {
+	for db != nil {
+		switch db.(type) {
+		case *Tx, *sql.Tx:
+			return true
+		}
+
+		wrapper, ok := db.(unwrapper)
+		if !ok {
+			break
+		}
+
+		db = wrapper.Unwrap()
+	}
+
+	return false
+}
+
+func isRetryable(dialect Dialect, er error) bool {
+	if dialect == nil {
+		dialect = DefaultDialect
+	}
+
+	classifier, ok := dialect.(RetryClassifier)
+	return ok && classifier.IsRetryable(er)
+}
+
+// rewrap wraps tx, begun on db, in the Instrument, Unscoped and WithContext
+// wrappers db has above the database or transaction it unwraps to. Contexts
+// are replaced with the one tx was begun with.
+func rewrap(db DbIsh, tx *Tx) DbIsh {
+	wrapper, ok := db.(unwrapper)
+	if !ok {
+		return tx
+	}
+
+	inner := rewrap(wrapper.Unwrap(), tx)
+
+	switch db := db.(type) {
+	case instrumentedDb:
+		return instrumentedDb{inner, db.observer}
+
+	case unscopedDb:
+		return unscopedDb{inner}
+
+	case contextDb:
+		return contextDb{inner, tx.ctx}
+	}
+
+	return inner
+}
@@ -0,0 +1,115 @@
+package crud
+
+import (
+	"context"
+)
+
+// Scannable is satisfied by pointers to structs implementing FieldBinder,
+// such as those generated by crudgen. It lets the typed functions below,
+// such as Select, allocate and scan values of T without reflection.
+type Scannable[T any] interface {
+	*T
+	FieldBinder
+}
+
+// Select runs q with args and scans every row into a T. Soft-deleted rows are
+// skipped, as by the generated fetch helpers, unless db is Unscoped.
+func Select[T any, PT Scannable[T]](ctx context.Context, db DbIsh, q string, args ...interface{}) ([]T, error) {
+	out := []T{}
+
+	er := each[T, PT](ctx, db, q, args, func(obj *T) bool {
+		out = append(out, *obj)
+		return true
+	})
+
+	if er != nil {
+		return nil, er
+	}
+
+	return out, nil
+}
+
+// All returns every row of table, leaving out soft-deleted rows unless db is
+// Unscoped.
+func All[T any, PT Scannable[T]](ctx context.Context, db DbIsh, table string) ([]T, error) {
+	return Select[T, PT](ctx, db, selectWhereQuery(db, table, PT(new(T)), ""))
+}
+
+// First runs q with args and returns its first row, or ErrNotFound if there
+// are none.
+func First[T any, PT Scannable[T]](ctx context.Context, db DbIsh, q string, args ...interface{}) (*T, error) {
+	var out *T
+
+	er := each[T, PT](ctx, db, q, args, func(obj *T) bool {
+		out = obj
+		return false
+	})
+
+	if er != nil {
+		return nil, er
+	}
+
+	if out == nil {
+		return nil, ErrNotFound
+	}
+
+	return out, nil
+}
+
+// One is like First, but returns ErrTooManyRows if q returns more than one
+// row.
+func One[T any, PT Scannable[T]](ctx context.Context, db DbIsh, q string, args ...interface{}) (*T, error) {
+	var out *T
+	tooMany := false
+
+	er := each[T, PT](ctx, db, q, args, func(obj *T) bool {
+		if out != nil {
+			tooMany = true
+			return false
+		}
+
+		out = obj
+		return true
+	})
+
+	switch {
+	case er != nil:
+		return nil, er
+
+	case tooMany:
+		return nil, ErrTooManyRows
+
+	case out == nil:
+		return nil, ErrNotFound
+	}
+
+	return out, nil
+}
+
+// each runs q and calls fn with each visible row scanned into a new T, until
+// fn returns false.
+func each[T any, PT Scannable[T]](ctx context.Context, db DbIsh, q string, args []interface{}, fn func(*T) bool) error {
+	rows, er := WithContext(ctx, db).Query(q, args...)
+	if er != nil {
+		return er
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		obj := new(T)
+
+		if er := Scan(rows, PT(obj)); er != nil {
+			return er
+		}
+
+		if enumerator, ok := interface{}(PT(obj)).(FieldEnumerator); ok && Hidden(db, enumerator) {
+			continue
+		}
+
+		if !fn(obj) {
+			return rows.Close()
+		}
+	}
+
+	return rows.Err()
+}
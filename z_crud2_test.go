@@ -2,6 +2,10 @@ package crud
 
 // AUTOGENERATED CODE. Regenerate by running crudgen.
 
+import (
+	"time"
+)
+
 func (self *Foo) BindFields(names []string, values []interface{}) {
 	for i, name := range names {
 		switch name {
@@ -41,6 +45,13 @@ func (self *Foo) EnumerateFields() (names []string, values []interface{}) {
 	return
 }
 
+func (self *Foo) Clone() FieldBinder {
+	clone := new(Foo)
+	*clone = *self
+
+	return clone
+}
+
 func (self *OptionalFoo) BindFields(names []string, values []interface{}) {
 	for i, name := range names {
 		switch name {
@@ -104,6 +115,53 @@ func (self *OptionalFoo) EnumerateFields() (names []string, values []interface{}
 	return
 }
 
+func (self *OptionalFoo) Clone() FieldBinder {
+	clone := new(OptionalFoo)
+	*clone = *self
+	if self.Bool != nil {
+		p1 := new(bool)
+		*p1 = *self.Bool
+		clone.Bool = p1
+	}
+	if self.Float32 != nil {
+		p2 := new(float32)
+		*p2 = *self.Float32
+		clone.Float32 = p2
+	}
+	if self.Float64 != nil {
+		p3 := new(float64)
+		*p3 = *self.Float64
+		clone.Float64 = p3
+	}
+	if self.Int16 != nil {
+		p4 := new(int16)
+		*p4 = *self.Int16
+		clone.Int16 = p4
+	}
+	if self.Int32 != nil {
+		p5 := new(int32)
+		*p5 = *self.Int32
+		clone.Int32 = p5
+	}
+	if self.Int64 != nil {
+		p6 := new(int64)
+		*p6 = *self.Int64
+		clone.Int64 = p6
+	}
+	if self.Int8 != nil {
+		p7 := new(int8)
+		*p7 = *self.Int8
+		clone.Int8 = p7
+	}
+	if self.String != nil {
+		p8 := new(string)
+		*p8 = *self.String
+		clone.String = p8
+	}
+
+	return clone
+}
+
 func (self *TimeFoo) BindFields(names []string, values []interface{}) {
 	for i, name := range names {
 		switch name {
@@ -131,6 +189,18 @@ func (self *TimeFoo) EnumerateFields() (names []string, values []interface{}) {
 	return
 }
 
+func (self *TimeFoo) Clone() FieldBinder {
+	clone := new(TimeFoo)
+	*clone = *self
+	if self.TimePtr != nil {
+		p1 := new(time.Time)
+		*p1 = *self.TimePtr
+		clone.TimePtr = p1
+	}
+
+	return clone
+}
+
 func (self *ModifiedFoo) BindFields(names []string, values []interface{}) {
 	for i, name := range names {
 		switch name {
@@ -169,3 +239,1094 @@ func (self *ModifiedFoo) EnumerateFields() (names []string, values []interface{}
 
 	return
 }
+
+func (self *ModifiedFoo) Clone() FieldBinder {
+	clone := new(ModifiedFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *Owner) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "owner_id":
+			values[i] = &self.Id
+
+		case "owner_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *Owner) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "owner_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "owner_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *Owner) Clone() FieldBinder {
+	clone := new(Owner)
+	*clone = *self
+
+	return clone
+}
+
+func (self *Pet) BindFields(names []string, values []interface{}) {
+	var nullOwner *NullStruct[Owner]
+
+	for i, name := range names {
+		switch name {
+
+		case "pet_id":
+			values[i] = &self.Id
+
+		case "pet_name":
+			values[i] = &self.Name
+
+		case "owner_id":
+			if nullOwner == nil {
+				nullOwner = NewNullStruct(&self.Owner, nil)
+			}
+			values[i] = NullField(nullOwner, &nullOwner.Value.Id)
+
+		case "owner_name":
+			if nullOwner == nil {
+				nullOwner = NewNullStruct(&self.Owner, nil)
+			}
+			values[i] = NullField(nullOwner, &nullOwner.Value.Name)
+
+		}
+	}
+}
+
+func (self *Pet) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 4)
+	values = make([]interface{}, 0, 4)
+
+	names = append(names, "pet_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "pet_name")
+	values = append(values, &self.Name)
+
+	names = append(names, "owner_id")
+	if self.Owner != nil {
+		values = append(values, &self.Owner.Id)
+	} else {
+		values = append(values, nil)
+	}
+
+	names = append(names, "owner_name")
+	if self.Owner != nil {
+		values = append(values, &self.Owner.Name)
+	} else {
+		values = append(values, nil)
+	}
+
+	return
+}
+
+func (self *Pet) Clone() FieldBinder {
+	clone := new(Pet)
+	*clone = *self
+	if self.Owner != nil {
+		p1 := new(Owner)
+		*p1 = *self.Owner
+		clone.Owner = p1
+	}
+
+	return clone
+}
+
+func (self *JSONFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "jfoo_id":
+			values[i] = &self.Id
+
+		case "jfoo_point":
+			values[i] = JSON(&self.Point)
+
+		case "jfoo_settings":
+			values[i] = JSON(&self.Settings)
+
+		case "jfoo_tags":
+			values[i] = JSON(&self.Tags)
+
+		}
+	}
+}
+
+func (self *JSONFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 4)
+	values = make([]interface{}, 0, 4)
+
+	names = append(names, "jfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "jfoo_point")
+	values = append(values, JSON(&self.Point))
+
+	names = append(names, "jfoo_settings")
+	values = append(values, JSON(&self.Settings))
+
+	names = append(names, "jfoo_tags")
+	values = append(values, JSON(&self.Tags))
+
+	return
+}
+
+func (self *JSONFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "json":
+		return []string{"jfoo_point", "jfoo_settings", "jfoo_tags"}
+
+	}
+
+	return nil
+}
+
+func (self *JSONFoo) Clone() FieldBinder {
+	clone := new(JSONFoo)
+	*clone = *self
+	if self.Point != nil {
+		p1 := new(JSONPoint)
+		*p1 = *self.Point
+		clone.Point = p1
+	}
+	if self.Settings != nil {
+		clone.Settings = make(map[string]string, len(self.Settings))
+		for k2, v3 := range self.Settings {
+			clone.Settings[k2] = v3
+		}
+	}
+	if self.Tags != nil {
+		clone.Tags = make([]string, len(self.Tags))
+		copy(clone.Tags, self.Tags)
+	}
+
+	return clone
+}
+
+func (self *ArrayFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "afoo_floats":
+			values[i] = Array(&self.Floats)
+
+		case "afoo_id":
+			values[i] = &self.Id
+
+		case "afoo_ints":
+			values[i] = Array(&self.Ints)
+
+		case "afoo_strings":
+			values[i] = Array(&self.Strings)
+
+		}
+	}
+}
+
+func (self *ArrayFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 4)
+	values = make([]interface{}, 0, 4)
+
+	names = append(names, "afoo_floats")
+	values = append(values, Array(&self.Floats))
+
+	names = append(names, "afoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "afoo_ints")
+	values = append(values, Array(&self.Ints))
+
+	names = append(names, "afoo_strings")
+	values = append(values, Array(&self.Strings))
+
+	return
+}
+
+func (self *ArrayFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "array":
+		return []string{"afoo_floats", "afoo_ints", "afoo_strings"}
+
+	}
+
+	return nil
+}
+
+func (self *ArrayFoo) Clone() FieldBinder {
+	clone := new(ArrayFoo)
+	*clone = *self
+	if self.Floats != nil {
+		clone.Floats = make([]float64, len(self.Floats))
+		copy(clone.Floats, self.Floats)
+	}
+	if self.Ints != nil {
+		clone.Ints = make([]*int64, len(self.Ints))
+		copy(clone.Ints, self.Ints)
+		for i1 := range self.Ints {
+			if self.Ints[i1] != nil {
+				p2 := new(int64)
+				*p2 = *self.Ints[i1]
+				clone.Ints[i1] = p2
+			}
+		}
+	}
+	if self.Strings != nil {
+		clone.Strings = make([]string, len(self.Strings))
+		copy(clone.Strings, self.Strings)
+	}
+
+	return clone
+}
+
+func (self *TextFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "xfoo_id":
+			values[i] = &self.Id
+
+		case "xfoo_prev":
+			values[i] = NullText("xfoo_prev", &self.Prev)
+
+		case "xfoo_status":
+			values[i] = Text("xfoo_status", &self.Status)
+
+		}
+	}
+}
+
+func (self *TextFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 3)
+	values = make([]interface{}, 0, 3)
+
+	names = append(names, "xfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "xfoo_prev")
+	values = append(values, NullText("xfoo_prev", &self.Prev))
+
+	names = append(names, "xfoo_status")
+	values = append(values, Text("xfoo_status", &self.Status))
+
+	return
+}
+
+func (self *TextFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "text":
+		return []string{"xfoo_prev", "xfoo_status"}
+
+	}
+
+	return nil
+}
+
+func (self *TextFoo) Clone() FieldBinder {
+	clone := new(TextFoo)
+	*clone = *self
+	if self.Prev != nil {
+		p1 := new(Status)
+		*p1 = *self.Prev
+		clone.Prev = p1
+	}
+
+	return clone
+}
+
+func (self *ConvFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "cfoo_discount":
+			values[i] = &self.Discount
+
+		case "cfoo_id":
+			values[i] = &self.Id
+
+		case "cfoo_price":
+			values[i] = &self.Price
+
+		}
+	}
+}
+
+func (self *ConvFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 3)
+	values = make([]interface{}, 0, 3)
+
+	names = append(names, "cfoo_discount")
+	values = append(values, &self.Discount)
+
+	names = append(names, "cfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "cfoo_price")
+	values = append(values, &self.Price)
+
+	return
+}
+
+func (self *ConvFoo) Clone() FieldBinder {
+	clone := new(ConvFoo)
+	*clone = *self
+	if self.Discount != nil {
+		p1 := new(Money)
+		*p1 = *self.Discount
+		clone.Discount = p1
+	}
+
+	return clone
+}
+
+func (self *NullZeroFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "zfoo_count":
+			values[i] = NullZero(&self.Count)
+
+		case "zfoo_id":
+			values[i] = &self.Id
+
+		case "zfoo_name":
+			values[i] = NullZero(&self.Name)
+
+		case "zfoo_price":
+			values[i] = NullZero(&self.Price)
+
+		case "zfoo_when":
+			values[i] = NullZero(&self.When)
+
+		}
+	}
+}
+
+func (self *NullZeroFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 5)
+	values = make([]interface{}, 0, 5)
+
+	names = append(names, "zfoo_count")
+	values = append(values, NullZero(&self.Count))
+
+	names = append(names, "zfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "zfoo_name")
+	values = append(values, NullZero(&self.Name))
+
+	names = append(names, "zfoo_price")
+	values = append(values, NullZero(&self.Price))
+
+	names = append(names, "zfoo_when")
+	values = append(values, NullZero(&self.When))
+
+	return
+}
+
+func (self *NullZeroFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "nullzero":
+		return []string{"zfoo_count", "zfoo_name", "zfoo_price", "zfoo_when"}
+
+	}
+
+	return nil
+}
+
+func (self *NullZeroFoo) Clone() FieldBinder {
+	clone := new(NullZeroFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *DefaultFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "dfoo_id":
+			values[i] = &self.Id
+
+		case "dfoo_name":
+			values[i] = &self.Name
+
+		case "dfoo_seq":
+			values[i] = &self.Seq
+
+		case "dfoo_token":
+			values[i] = &self.Token
+
+		}
+	}
+}
+
+func (self *DefaultFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 4)
+	values = make([]interface{}, 0, 4)
+
+	names = append(names, "dfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "dfoo_name")
+	values = append(values, &self.Name)
+
+	names = append(names, "dfoo_seq")
+	values = append(values, &self.Seq)
+
+	names = append(names, "dfoo_token")
+	values = append(values, &self.Token)
+
+	return
+}
+
+func (self *DefaultFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "dbdefault":
+		return []string{"dfoo_seq", "dfoo_token"}
+
+	}
+
+	return nil
+}
+
+func (self *DefaultFoo) Clone() FieldBinder {
+	clone := new(DefaultFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *StrKeyFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "sfoo_id":
+			values[i] = &self.Id
+
+		case "sfoo_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *StrKeyFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "sfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "sfoo_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *StrKeyFoo) Clone() FieldBinder {
+	clone := new(StrKeyFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *SmallKeyFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "kfoo_id":
+			values[i] = &self.Id
+
+		case "kfoo_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *SmallKeyFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "kfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "kfoo_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *SmallKeyFoo) Clone() FieldBinder {
+	clone := new(SmallKeyFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *StampFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "stfoo_created":
+			values[i] = &self.Created
+
+		case "stfoo_id":
+			values[i] = &self.Id
+
+		case "stfoo_name":
+			values[i] = &self.Name
+
+		case "stfoo_updated":
+			values[i] = &self.Updated
+
+		}
+	}
+}
+
+func (self *StampFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 4)
+	values = make([]interface{}, 0, 4)
+
+	names = append(names, "stfoo_created")
+	values = append(values, &self.Created)
+
+	names = append(names, "stfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "stfoo_name")
+	values = append(values, &self.Name)
+
+	names = append(names, "stfoo_updated")
+	values = append(values, &self.Updated)
+
+	return
+}
+
+func (self *StampFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "created":
+		return []string{"stfoo_created"}
+
+	case "updated":
+		return []string{"stfoo_updated"}
+
+	}
+
+	return nil
+}
+
+func (self *StampFoo) Clone() FieldBinder {
+	clone := new(StampFoo)
+	*clone = *self
+	if self.Updated != nil {
+		p1 := new(time.Time)
+		*p1 = *self.Updated
+		clone.Updated = p1
+	}
+
+	return clone
+}
+
+func (self *SoftFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "sofoo_deleted":
+			values[i] = &self.Deleted
+
+		case "sofoo_id":
+			values[i] = &self.Id
+
+		case "sofoo_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *SoftFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 3)
+	values = make([]interface{}, 0, 3)
+
+	names = append(names, "sofoo_deleted")
+	values = append(values, &self.Deleted)
+
+	names = append(names, "sofoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "sofoo_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *SoftFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "deleted":
+		return []string{"sofoo_deleted"}
+
+	}
+
+	return nil
+}
+
+func (self *SoftFoo) Clone() FieldBinder {
+	clone := new(SoftFoo)
+	*clone = *self
+	if self.Deleted != nil {
+		p1 := new(time.Time)
+		*p1 = *self.Deleted
+		clone.Deleted = p1
+	}
+
+	return clone
+}
+
+func (self *HookFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "hfoo_id":
+			values[i] = &self.Id
+
+		case "hfoo_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *HookFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "hfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "hfoo_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *HookFoo) Clone() FieldBinder {
+	clone := new(HookFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *SecretFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "secfoo_id":
+			values[i] = &self.Id
+
+		case "secfoo_password":
+			values[i] = &self.Password
+
+		case "secfoo_user":
+			values[i] = &self.User
+
+		}
+	}
+}
+
+func (self *SecretFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 3)
+	values = make([]interface{}, 0, 3)
+
+	names = append(names, "secfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "secfoo_password")
+	values = append(values, &self.Password)
+
+	names = append(names, "secfoo_user")
+	values = append(values, &self.User)
+
+	return
+}
+
+func (self *SecretFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "secret":
+		return []string{"secfoo_password"}
+
+	}
+
+	return nil
+}
+
+func (self *SecretFoo) Clone() FieldBinder {
+	clone := new(SecretFoo)
+	*clone = *self
+
+	return clone
+}
+
+func (self *Customer) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "customer_id":
+			values[i] = &self.Id
+
+		case "customer_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *Customer) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "customer_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "customer_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *Customer) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "pk":
+		return []string{"customer_id"}
+
+	}
+
+	return nil
+}
+
+func (self *Customer) CrudRelation(name string) *Relation {
+	switch name {
+
+	case "Orders":
+		return &Relation{
+			Kind:   HasMany,
+			Table:  "orders",
+			Key:    "customer_id",
+			Column: "order_customer_id",
+			New: func() FieldBinder {
+				return new(Order)
+			},
+			Attach: func(related []FieldBinder) {
+				self.Orders = make([]*Order, len(related))
+				for i, obj := range related {
+					self.Orders[i] = obj.(*Order)
+				}
+			},
+		}
+
+	}
+
+	return nil
+}
+
+func (self *Customer) Clone() FieldBinder {
+	clone := new(Customer)
+	*clone = *self
+
+	return clone
+}
+
+func (self *Order) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "order_customer_id":
+			values[i] = &self.CustomerId
+
+		case "order_deleted_at":
+			values[i] = &self.Deleted
+
+		case "order_id":
+			values[i] = &self.Id
+
+		case "order_total":
+			values[i] = &self.Total
+
+		}
+	}
+}
+
+func (self *Order) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 4)
+	values = make([]interface{}, 0, 4)
+
+	names = append(names, "order_customer_id")
+	values = append(values, &self.CustomerId)
+
+	names = append(names, "order_deleted_at")
+	values = append(values, &self.Deleted)
+
+	names = append(names, "order_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "order_total")
+	values = append(values, &self.Total)
+
+	return
+}
+
+func (self *Order) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "deleted":
+		return []string{"order_deleted_at"}
+
+	case "pk":
+		return []string{"order_id"}
+
+	}
+
+	return nil
+}
+
+func (self *Order) CrudRelation(name string) *Relation {
+	switch name {
+
+	case "Customer":
+		return &Relation{
+			Kind:   BelongsTo,
+			Table:  "customer",
+			Key:    "order_customer_id",
+			Column: "customer_id",
+			New: func() FieldBinder {
+				return new(Customer)
+			},
+			Attach: func(related []FieldBinder) {
+				self.Customer = nil
+				if len(related) > 0 {
+					self.Customer = related[0].(*Customer)
+				}
+			},
+		}
+
+	}
+
+	return nil
+}
+
+func (self *Order) Clone() FieldBinder {
+	clone := new(Order)
+	*clone = *self
+	if self.CustomerId != nil {
+		p1 := new(int64)
+		*p1 = *self.CustomerId
+		clone.CustomerId = p1
+	}
+	if self.Deleted != nil {
+		p2 := new(time.Time)
+		*p2 = *self.Deleted
+		clone.Deleted = p2
+	}
+
+	return clone
+}
+
+func (self *Post) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "post_id":
+			values[i] = &self.Id
+
+		case "post_title":
+			values[i] = &self.Title
+
+		}
+	}
+}
+
+func (self *Post) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "post_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "post_title")
+	values = append(values, &self.Title)
+
+	return
+}
+
+func (self *Post) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "pk":
+		return []string{"post_id"}
+
+	}
+
+	return nil
+}
+
+func (self *Post) CrudRelation(name string) *Relation {
+	switch name {
+
+	case "Tags":
+		return &Relation{
+			Kind:      ManyToMany,
+			Table:     "tag",
+			Key:       "post_id",
+			Column:    "tag_id",
+			JoinTable: "post_tag",
+			New: func() FieldBinder {
+				return new(Tag)
+			},
+			Attach: func(related []FieldBinder) {
+				self.Tags = make([]*Tag, len(related))
+				for i, obj := range related {
+					self.Tags[i] = obj.(*Tag)
+				}
+			},
+		}
+
+	}
+
+	return nil
+}
+
+func (self *Post) Clone() FieldBinder {
+	clone := new(Post)
+	*clone = *self
+
+	return clone
+}
+
+func (self *Tag) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "deleted_at":
+			values[i] = &self.Deleted
+
+		case "tag_id":
+			values[i] = &self.Id
+
+		case "tag_name":
+			values[i] = &self.Name
+
+		}
+	}
+}
+
+func (self *Tag) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 3)
+	values = make([]interface{}, 0, 3)
+
+	names = append(names, "deleted_at")
+	values = append(values, &self.Deleted)
+
+	names = append(names, "tag_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "tag_name")
+	values = append(values, &self.Name)
+
+	return
+}
+
+func (self *Tag) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "deleted":
+		return []string{"deleted_at"}
+
+	case "pk":
+		return []string{"tag_id"}
+
+	}
+
+	return nil
+}
+
+func (self *Tag) Clone() FieldBinder {
+	clone := new(Tag)
+	*clone = *self
+	if self.Deleted != nil {
+		p1 := new(time.Time)
+		*p1 = *self.Deleted
+		clone.Deleted = p1
+	}
+
+	return clone
+}
+
+func (self *DefaultOnlyFoo) BindFields(names []string, values []interface{}) {
+	for i, name := range names {
+		switch name {
+
+		case "dfoo_id":
+			values[i] = &self.Id
+
+		case "dfoo_token":
+			values[i] = &self.Token
+
+		}
+	}
+}
+
+func (self *DefaultOnlyFoo) EnumerateFields() (names []string, values []interface{}) {
+	names = make([]string, 0, 2)
+	values = make([]interface{}, 0, 2)
+
+	names = append(names, "dfoo_id")
+	values = append(values, &self.Id)
+
+	names = append(names, "dfoo_token")
+	values = append(values, &self.Token)
+
+	return
+}
+
+func (self *DefaultOnlyFoo) FlaggedFields(flag string) []string {
+	switch flag {
+
+	case "dbdefault":
+		return []string{"dfoo_token"}
+
+	}
+
+	return nil
+}
+
+func (self *DefaultOnlyFoo) Clone() FieldBinder {
+	clone := new(DefaultOnlyFoo)
+	*clone = *self
+
+	return clone
+}